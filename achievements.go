@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// achievementsFile records when each player unlocked each achievement.
+const achievementsFile = "achievements.json"
+
+// achievement is a long-term goal, checked after every run against the
+// player's lifetime stats and the run just played.
+type achievement struct {
+	ID          string // key in achievementsFile; never change it once released
+	Name        string
+	Description string
+	Earned      func(ps *playerStats, r runResult) bool
+}
+
+// achievements are every achievement there is, in the order the viewer
+// lists them. New ones only need an entry here.
+var achievements = []achievement{
+	{"first-clear", "First Drop", "Clear your first combo", func(ps *playerStats, r runResult) bool {
+		return ps.Cleared >= 1
+	}},
+	{"flawless-run", "Flawless", "Finish a run with every combo cleared and no wrong keys", func(ps *playerStats, r runResult) bool {
+		if len(r.Outcomes) == 0 || r.Mistakes > 0 {
+			return false
+		}
+		for _, o := range r.Outcomes {
+			if o != outcomeCleared {
+				return false
+			}
+		}
+		return true
+	}},
+	{"combos-100", "Centurion", "Clear 100 combos", func(ps *playerStats, r runResult) bool {
+		return ps.Cleared >= 100
+	}},
+	{"combos-1000", "Veteran", "Clear 1,000 combos", func(ps *playerStats, r runResult) bool {
+		return ps.Cleared >= 1000
+	}},
+	{"sub-second", "Lightning Reflexes", "Clear a combo in under a second", func(ps *playerStats, r runResult) bool {
+		return ps.Records.FastestCombo != "" && ps.Records.FastestSeconds < 1
+	}},
+	{"streak-25", "Unbroken", "Clear 25 combos in a row without a wrong key", func(ps *playerStats, r runResult) bool {
+		return ps.Records.LongestStreak >= 25
+	}},
+	{"perfect-10", "Perfectionist", "Earn 10 perfect timed combos", func(ps *playerStats, r runResult) bool {
+		return ps.Perfect >= 10
+	}},
+	{"days-7", "Dedicated", "Play on 7 days in a row", func(ps *playerStats, r runResult) bool {
+		return ps.DayStreak >= 7
+	}},
+	{"gauntlet", "Ran the Gauntlet", "Clear the gauntlet", func(ps *playerStats, r runResult) bool {
+		return r.Mode == "gauntlet" && len(r.Outcomes) > 0 && r.Outcomes[0] == outcomeCleared
+	}},
+	{"ladder-10", "Speed Demon", "Reach level 10 on the speed ladder", func(ps *playerStats, r runResult) bool {
+		return r.Ladder != nil && r.Ladder.Level >= 10
+	}},
+}
+
+// loadAchievements reads achievementsFile: for each player, when each
+// achievement was unlocked. A missing file unlocks nothing.
+func loadAchievements() (map[string]map[string]time.Time, error) {
+	all := map[string]map[string]time.Time{}
+	data, err := os.ReadFile(achievementsFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return all, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("%s: %w", achievementsFile, err)
+	}
+	return all, nil
+}
+
+// evaluateAchievements returns the achievements ps and the run r earn that
+// are not yet in unlocked.
+func evaluateAchievements(ps *playerStats, r runResult, unlocked map[string]time.Time) []achievement {
+	var earned []achievement
+	for _, a := range achievements {
+		if _, ok := unlocked[a.ID]; !ok && a.Earned(ps, r) {
+			earned = append(earned, a)
+		}
+	}
+	return earned
+}
+
+// recordAchievements unlocks for user whatever ps and r newly earn, saves
+// them and returns their names.
+func recordAchievements(user string, ps *playerStats, r runResult) ([]string, error) {
+	all, err := loadAchievements()
+	if err != nil {
+		return nil, err
+	}
+	if all[user] == nil {
+		all[user] = map[string]time.Time{}
+	}
+	earned := evaluateAchievements(ps, r, all[user])
+	if len(earned) == 0 {
+		return nil, nil
+	}
+	var names []string
+	for _, a := range earned {
+		all[user][a.ID] = time.Now()
+		names = append(names, a.Name)
+	}
+	data, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return names, writeFileAtomic(achievementsFile, data)
+}
+
+// achievementDescription returns the description of the achievement named
+// name, for the summary.
+func achievementDescription(name string) string {
+	for _, a := range achievements {
+		if a.Name == name {
+			return a.Description
+		}
+	}
+	return ""
+}
+
+// printAchievements writes every achievement to w, with the date user
+// unlocked it or as still locked.
+func printAchievements(w io.Writer, user string) error {
+	all, err := loadAchievements()
+	if err != nil {
+		return err
+	}
+	unlocked := all[user]
+	fmt.Fprintf(w, "Achievements for %s: %d of %d unlocked\n\n", user, len(unlocked), len(achievements))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, a := range achievements {
+		status := "locked"
+		if at, ok := unlocked[a.ID]; ok {
+			status = "✓ " + at.Local().Format("2006-01-02")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", status, a.Name, a.Description)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/nsf/termbox-go"
+)
+
+// maxListWidth caps the width of the combo list pane in browseCombos.
+const maxListWidth = 36
+
+// browseCombos is the -list browser: the combos down the left, and the
+// selected one's name, type and arrow art on the right. Up and Down move the
+// selection, Enter drills the selected combo until it is cleared or quit,
+// and Esc leaves.
+func browseCombos(combos []combination) error {
+	if len(combos) == 0 {
+		return fmt.Errorf("no combos to list")
+	}
+	if err := termbox.Init(); err != nil {
+		return err
+	}
+	selected, top := 0, 0
+	for {
+		_, height := termbox.Size()
+		rows := max(1, height-2)
+		top = min(max(top, selected-rows+1), selected)
+		drawComboList(combos, selected, top, rows)
+		termbox.Flush()
+
+		ev := <-input.Events()
+		if ev.Type == termbox.EventError {
+			termbox.Close()
+			return ev.Err
+		}
+		if ev.Type != termbox.EventKey {
+			continue
+		}
+		switch {
+		case ev.Key == termbox.KeyEnter:
+			termbox.Close()
+			drillCombo(combos[selected])
+			if err := termbox.Init(); err != nil {
+				return err
+			}
+		case isQuitKey(ev):
+			termbox.Close()
+			return nil
+		case ev.Key == termbox.KeyArrowUp:
+			selected = max(0, selected-1)
+		case ev.Key == termbox.KeyArrowDown:
+			selected = min(len(combos)-1, selected+1)
+		case ev.Key == termbox.KeyPgup:
+			selected = max(0, selected-rows)
+		case ev.Key == termbox.KeyPgdn:
+			selected = min(len(combos)-1, selected+rows)
+		case ev.Key == termbox.KeyHome:
+			selected = 0
+		case ev.Key == termbox.KeyEnd:
+			selected = len(combos) - 1
+		}
+	}
+}
+
+// drawComboList draws the browser's two panes into the termbox back buffer,
+// showing rows combos of the list from top.
+func drawComboList(combos []combination, selected, top, rows int) {
+	termbox.Clear(termbox.ColorDefault, termbox.ColorDefault)
+	listWidth := 0
+	for _, combo := range combos {
+		listWidth = max(listWidth, len([]rune(combo.Name)))
+	}
+	listWidth = min(listWidth, maxListWidth)
+
+	for i := top; i < min(len(combos), top+rows); i++ {
+		name := []rune(combos[i].Name)
+		if len(name) > listWidth {
+			name = append(name[:listWidth-1], '…')
+		}
+		fg, bg := termbox.ColorDefault, termbox.ColorDefault
+		if i == selected {
+			fg, bg = termbox.ColorBlack, currentTheme().Next
+		}
+		drawString(0, i-top, fmt.Sprintf("%-*s", listWidth, string(name)), fg, bg)
+	}
+
+	combo := combos[selected]
+	x := listWidth + 3
+	drawString(x, 0, combo.Name, termbox.ColorDefault|termbox.AttrBold, termbox.ColorDefault)
+	if combo.Type != "" {
+		drawString(x, 1, combo.Type, termbox.ColorDefault, termbox.ColorDefault)
+	}
+	drawString(x, 2, combo.Sequence, termbox.ColorDefault, termbox.ColorDefault)
+	// Nothing counts as entered, so every arrow is drawn alike.
+	printArrows(x, 4, arrowSequenceFromCombination(combo.Sequence), -1, -1)
+
+	_, height := termbox.Size()
+	drawString(0, height-1, fmt.Sprintf("%d/%d  Up/Down to browse, Enter to drill, Esc to leave", selected+1, len(combos)), termbox.ColorDefault, termbox.ColorDefault)
+}
+
+// drillCombo plays combo over and over until the player quits it. Drill
+// scores don't count toward anything.
+func drillCombo(combo combination) {
+	if err := screen.Open(); err != nil {
+		fmt.Fprintln(console, "Failed to initialize the display:", err)
+		return
+	}
+	defer screen.Close()
+	seq := arrowSequenceFromCombination(combo.Sequence)
+	score := 0
+	for n := 1; ; n++ {
+		if o, _ := processSequence(seq, &score, fmt.Sprintf("Drill: %s (#%d, Esc to go back)", combo.Name, n), poolProgress{}); o == outcomeFailed {
+			return
+		}
+	}
+}
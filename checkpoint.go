@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// checkpointFile is where long endless runs are periodically saved.
+const checkpointFile = "checkpoint.json"
+
+// checkpoint is a lightweight snapshot of an endless run in progress. It is
+// removed when the run ends normally, so one left on disk means the last
+// session crashed or was killed.
+type checkpoint struct {
+	User      string    `json:"user"`
+	Started   time.Time `json:"started"`
+	Saved     time.Time `json:"saved"`
+	Score     int       `json:"score"`
+	Completed int       `json:"combos_completed"` // cleared, not skipped
+}
+
+// saveCheckpoint writes cp to checkpointFile.
+func saveCheckpoint(cp checkpoint) error {
+	cp.Saved = time.Now()
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(checkpointFile, data)
+}
+
+// loadCheckpoint reads the checkpoint left by an unfinished endless run.
+func loadCheckpoint() (*checkpoint, error) {
+	data, err := os.ReadFile(checkpointFile)
+	if err != nil {
+		return nil, err
+	}
+	var cp checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, err
+	}
+	return &cp, nil
+}
+
+// offerCheckpoint asks whether to show the stats of an endless run that did
+// not finish cleanly, then discards its checkpoint.
+func offerCheckpoint() {
+	cp, err := loadCheckpoint()
+	if err != nil {
+		return
+	}
+	defer os.Remove(checkpointFile)
+
+	fmt.Fprint(console, "Your last endless session did not finish cleanly. View its stats? [y/N]: ")
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Scan()
+	if !strings.EqualFold(strings.TrimSpace(scanner.Text()), "y") {
+		return
+	}
+	fmt.Fprintf(console, "Player: %s\n", cp.User)
+	fmt.Fprintf(console, "Started: %s (last saved %s)\n", cp.Started.Format(time.DateTime), cp.Saved.Format(time.DateTime))
+	fmt.Fprintf(console, "Combos completed: %d\n", cp.Completed)
+	fmt.Fprintf(console, "Score: %d\n", cp.Score)
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// configFile holds a player's default flag values.
+const configFile = "config.json"
+
+// Config is the contents of configFile. Flags maps flag names, without the
+// dash, to the value to use when the flag isn't given on the command line,
+// e.g. {"flags": {"count": 20, "remap": "U=w,D=s,L=a,R=d", "shake": true}}.
+// Modes overrides those defaults for single modes, keyed by the mode names
+// used in results, e.g. {"modes": {"random": {"count": 15, "length": 5},
+// "timed": {"time": "45s"}}}.
+type Config struct {
+	Flags map[string]any          `json:"flags"`
+	Modes map[string]modeDefaults `json:"modes,omitempty"`
+}
+
+// modeDefaults are one mode's defaults from configFile. Zero fields fall
+// back to the matching flag.
+type modeDefaults struct {
+	Count  int    `json:"count,omitempty"`  // -count
+	Length int    `json:"length,omitempty"` // -length, for random and practice
+	Time   string `json:"time,omitempty"`   // -time, for timed, e.g. "45s"
+}
+
+// set returns the names of the fields d sets.
+func (d modeDefaults) set() []string {
+	var names []string
+	if d.Count != 0 {
+		names = append(names, "count")
+	}
+	if d.Length != 0 {
+		names = append(names, "length")
+	}
+	if d.Time != "" {
+		names = append(names, "time")
+	}
+	return names
+}
+
+// modeSettings are the modes configFile may set defaults for, and the
+// modeDefaults fields each of them reads.
+var modeSettings = map[string][]string{
+	"json":     {"count"},
+	"random":   {"count", "length"},
+	"timed":    {"count", "time"},
+	"practice": {"count", "length"},
+}
+
+// modeConfig holds configFile's per-mode defaults, and cmdlineFlags the
+// flags given on the command line, which beat them.
+var (
+	modeConfig   map[string]modeDefaults
+	cmdlineFlags = map[string]bool{}
+)
+
+// configSkipped are one-shot actions and per-session files that make no
+// sense as defaults: -init-config leaves them out and loadConfig refuses
+// them, so a config file can't turn every launch into one of them.
+var configSkipped = map[string]bool{
+	"generate": true, "out": true, "overwrite": true, "diff": true, "lint": true, "study": true,
+	"find": true, "leaderboard": true, "stats": true, "achievements": true,
+	"list": true, "web": true, "hot-seat": true,
+	"init-config": true, "play-macro": true, "record": true, "ghost": true,
+}
+
+// loadConfig reads configFile, if any, and applies its values to every flag
+// not already set on the command line.
+func loadConfig() error {
+	data, err := os.ReadFile(configFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	// Numbers are kept as written, since float64 would print a large count
+	// or duration as 1e+06, which the flags can't parse.
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var cfg Config
+	if err := dec.Decode(&cfg); err != nil {
+		return fmt.Errorf("%s: %w", configFile, err)
+	}
+	flag.Visit(func(f *flag.Flag) { cmdlineFlags[f.Name] = true })
+	for name, value := range cfg.Flags {
+		if flag.Lookup(name) == nil {
+			return fmt.Errorf("%s: unknown flag %q", configFile, name)
+		}
+		if configSkipped[name] {
+			return fmt.Errorf("%s: flag %q can't be set here, only on the command line", configFile, name)
+		}
+		if cmdlineFlags[name] {
+			continue
+		}
+		if err := flag.Set(name, configValue(flag.Lookup(name), value)); err != nil {
+			return fmt.Errorf("%s: flag %q: %w", configFile, name, err)
+		}
+	}
+	for mode, d := range cfg.Modes {
+		used, ok := modeSettings[mode]
+		if !ok {
+			return fmt.Errorf("%s: mode %q has no settings of its own", configFile, mode)
+		}
+		var unused []string
+		for _, s := range d.set() {
+			if !slices.Contains(used, s) {
+				unused = append(unused, s)
+			}
+		}
+		if len(unused) > 0 {
+			return fmt.Errorf("%s: mode %q doesn't use %s", configFile, mode, strings.Join(unused, ", "))
+		}
+		if d.Count < 0 || d.Length < 0 {
+			return fmt.Errorf("%s: mode %q: count and length must not be negative", configFile, mode)
+		}
+		if d.Time != "" {
+			if t, err := time.ParseDuration(d.Time); err != nil || t <= 0 {
+				return fmt.Errorf("%s: mode %q: invalid time %q", configFile, mode, d.Time)
+			}
+		}
+	}
+	modeConfig = cfg.Modes
+	return nil
+}
+
+// configValue returns value from configFile as flag f's command-line form.
+// A plain number for a duration flag is taken as nanoseconds, as
+// time.Duration itself would marshal it.
+func configValue(f *flag.Flag, value any) string {
+	n, ok := value.(json.Number)
+	if !ok {
+		return fmt.Sprint(value)
+	}
+	if g, ok := f.Value.(flag.Getter); ok {
+		if _, isDuration := g.Get().(time.Duration); isDuration {
+			if ns, err := n.Int64(); err == nil {
+				return time.Duration(ns).String()
+			}
+		}
+	}
+	return n.String()
+}
+
+// modeCount returns how many combos mode plays: -count when given on the
+// command line, otherwise the mode's configured count if any, else -count.
+func modeCount(mode string) int {
+	if d := modeConfig[mode]; d.Count > 0 && !cmdlineFlags["count"] {
+		return d.Count
+	}
+	return *count
+}
+
+// modeLength returns the random sequence length for mode, like modeCount.
+func modeLength(mode string) int {
+	if d := modeConfig[mode]; d.Length > 0 && !cmdlineFlags["length"] {
+		return d.Length
+	}
+	return *randomLen
+}
+
+// modeTime returns the time limit for mode, like modeCount.
+func modeTime(mode string) time.Duration {
+	if d := modeConfig[mode]; d.Time != "" && !cmdlineFlags["time"] {
+		// loadConfig has already checked it parses.
+		t, _ := time.ParseDuration(d.Time)
+		return t
+	}
+	return *timeLimit
+}
+
+// writeDefaultConfig creates configFile listing every flag with its default
+// value, as a starting point to edit. An existing file is left alone.
+func writeDefaultConfig() error {
+	if fileExists(configFile) {
+		return fmt.Errorf("%s already exists", configFile)
+	}
+	cfg := Config{Flags: map[string]any{}}
+	flag.VisitAll(func(f *flag.Flag) {
+		if configSkipped[f.Name] {
+			return
+		}
+		// Keep booleans and numbers typed so the file reads naturally.
+		if f.DefValue == "true" || f.DefValue == "false" {
+			cfg.Flags[f.Name] = f.DefValue == "true"
+		} else if n, err := strconv.ParseFloat(f.DefValue, 64); err == nil {
+			cfg.Flags[f.Name] = n
+		} else {
+			cfg.Flags[f.Name] = f.DefValue
+		}
+	})
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(configFile, append(data, '\n'))
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ghostRace races the live player against the splits of a recorded run.
+type ghostRace struct {
+	name   string          // macro file the ghost came from
+	splits []time.Duration // when the ghost cleared each combo
+	start  time.Time       // when the live run started
+	player []time.Duration // when the player cleared each combo so far
+	tick   *time.Ticker
+}
+
+// ghost is the active race for -ghost, or nil.
+var ghost *ghostRace
+
+// ghostBarWidth is the number of cells in each lane's progress bar.
+const ghostBarWidth = 20
+
+// newGhostRace prepares a race against the run recorded in m.
+func newGhostRace(name string, m *macro) (*ghostRace, error) {
+	if len(m.Splits) == 0 {
+		return nil, fmt.Errorf("%s has no combo splits; record it again with -record", name)
+	}
+	g := &ghostRace{name: name}
+	for _, ms := range m.Splits {
+		g.splits = append(g.splits, time.Duration(ms)*time.Millisecond)
+	}
+	return g, nil
+}
+
+// begin starts the race clock and the ticker that keeps the ghost moving.
+func (g *ghostRace) begin() {
+	g.start = time.Now()
+	g.tick = time.NewTicker(100 * time.Millisecond)
+}
+
+// cleared records that the player cleared a combo.
+func (g *ghostRace) cleared() {
+	g.player = append(g.player, time.Since(g.start))
+}
+
+// ghostCleared returns how many combos the ghost had cleared at elapsed.
+func (g *ghostRace) ghostCleared(elapsed time.Duration) int {
+	n := 0
+	for n < len(g.splits) && g.splits[n] <= elapsed {
+		n++
+	}
+	return n
+}
+
+// lines returns the two progress lanes for the header.
+func (g *ghostRace) lines() []string {
+	total := len(g.splits)
+	lane := func(label string, done int) string {
+		filled := ghostBarWidth * min(done, total) / total
+		return fmt.Sprintf("%-5s [%s%s] %d/%d", label, strings.Repeat("█", filled), strings.Repeat("░", ghostBarWidth-filled), done, total)
+	}
+	return []string{
+		lane("You", len(g.player)),
+		lane("Ghost", g.ghostCleared(time.Since(g.start))),
+	}
+}
+
+// verdict declares the winner once the run is over: whoever cleared more of
+// the ghost's combos, and on a tie whoever cleared the last of them sooner.
+func (g *ghostRace) verdict() string {
+	g.tick.Stop()
+	total := len(g.splits)
+	mine := min(len(g.player), total)
+	switch {
+	case mine < total:
+		return fmt.Sprintf("The ghost wins: it cleared %d combos to your %d.", total, mine)
+	case g.player[total-1] < g.splits[total-1]:
+		return fmt.Sprintf("You beat the ghost by %.2f seconds!", (g.splits[total-1] - g.player[total-1]).Seconds())
+	case g.player[total-1] > g.splits[total-1]:
+		return fmt.Sprintf("The ghost wins by %.2f seconds.", (g.player[total-1] - g.splits[total-1]).Seconds())
+	}
+	return "A dead heat with the ghost!"
+}
+
+// ghostLines returns the race lanes, or nothing without -ghost.
+func ghostLines() []string {
+	if ghost == nil {
+		return nil
+	}
+	return ghost.lines()
+}
+
+// ghostTick fires while a race is on so the ghost's lane advances between
+// key presses. It is nil, and never fires, without -ghost.
+func ghostTick() <-chan time.Time {
+	if ghost == nil || ghost.tick == nil {
+		return nil
+	}
+	return ghost.tick.C
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// hotSeatPlayers splits the -hot-seat value into player names, dropping
+// blanks.
+func hotSeatPlayers(value string) []string {
+	var players []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			players = append(players, name)
+		}
+	}
+	return players
+}
+
+// playHotSeat has players take turns at the menu option choice on one
+// keyboard, each dealt the same combos from seed, then prints the standings.
+// With -webhook, each turn's result is posted as soon as it ends.
+// Before each turn a ready check waits for the incoming player, so their
+// clock doesn't start while the keyboard is changing hands.
+func playHotSeat(choice string, players []string, seed int64) {
+	var results []runResult
+	for _, player := range players {
+		if !readyCheck(player) {
+			logf("Hot-seat session stopped.\n")
+			break
+		}
+		rng = rand.New(rand.NewSource(seed))
+		result, ok := playMode(choice, player)
+		if !ok {
+			fmt.Fprintln(console, "Invalid option, please restart the program.")
+			return
+		}
+		fmt.Printf("%s: %s points in %s seconds\n", player, fmtInt(result.Score), fmtFloat(result.Elapsed, 2))
+		for _, name := range result.Achievements {
+			fmt.Printf("%s unlocked: %s (%s)\n", player, name, achievementDescription(name))
+		}
+		if *webhook != "" {
+			if err := postResult(*webhook, result); err != nil {
+				fmt.Fprintln(console, "Webhook failed:", err)
+			}
+		}
+		results = append(results, result)
+	}
+	if len(results) < 2 {
+		return
+	}
+
+	// Equal scores go to the faster run, then to whoever played first.
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Elapsed < results[j].Elapsed
+	})
+	fmt.Println("\nHot-seat standings")
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "#\tPlayer\tScore\tTime\tMistakes")
+	for rank, r := range results {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%ss\t%s\n", rank+1, r.User, fmtInt(r.Score), fmtFloat(r.Elapsed, 2), fmtInt(r.Mistakes))
+	}
+	tw.Flush()
+}
+
+// readyCheck shows the handoff screen for player and waits for Enter. Keys
+// still buffered from the previous turn are dropped first, so only a fresh
+// press starts the turn. It returns false if the player quits instead.
+func readyCheck(player string) bool {
+	if err := screen.Open(); err != nil {
+		fmt.Fprintln(console, "Failed to initialize the display:", err)
+		return false
+	}
+	defer screen.Close()
+
+drain:
+	for {
+		select {
+		case <-input.Events():
+		default:
+			break drain
+		}
+	}
+	screen.Render(frame{
+		Title:    fmt.Sprintf("Pass to %s — press Enter when ready", player),
+		Hint:     -1,
+		Feedback: "Enter to start, Esc to end the session",
+		Fresh:    true,
+	})
+	return awaitEnter()
+}
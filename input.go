@@ -0,0 +1,69 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/nsf/termbox-go"
+)
+
+// InputSource delivers terminal events to the play loops.
+type InputSource interface {
+	Events() <-chan termbox.Event
+}
+
+// termboxInput is the InputSource backed by the terminal. A single goroutine
+// polls termbox for the lifetime of the process, so events are never split
+// between competing pollers across combos or termbox sessions.
+type termboxInput struct{}
+
+var (
+	termboxEvents = make(chan termbox.Event)
+	termboxPump   sync.Once
+)
+
+// Events starts the polling goroutine on first use and returns its channel.
+func (termboxInput) Events() <-chan termbox.Event {
+	termboxPump.Do(func() {
+		go func() {
+			for {
+				termboxEvents <- termbox.PollEvent()
+			}
+		}()
+	})
+	return termboxEvents
+}
+
+// burstInput wraps another InputSource and drops key events that arrive within
+// window of the previous key event. Human key presses, including auto-repeat,
+// are tens of milliseconds apart, so anything closer is a paste or a burst of
+// buffered input and would otherwise register as a cascade of wrong keys.
+// The first key of a burst is kept; non-key events always pass through.
+type burstInput struct {
+	events chan termbox.Event
+}
+
+// newBurstInput starts filtering src. A zero window disables filtering.
+func newBurstInput(src InputSource, window time.Duration) *burstInput {
+	b := &burstInput{events: make(chan termbox.Event, 64)}
+	go func() {
+		var last time.Time
+		for ev := range src.Events() {
+			if ev.Type == termbox.EventKey && window > 0 {
+				now := time.Now()
+				inBurst := !last.IsZero() && now.Sub(last) < window
+				last = now
+				if inBurst {
+					continue
+				}
+			}
+			b.events <- ev
+		}
+	}()
+	return b
+}
+
+// Events returns the filtered event channel.
+func (b *burstInput) Events() <-chan termbox.Event {
+	return b.events
+}
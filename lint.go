@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// lintFlag is the value of -lint: a pack to check, or "true" when the flag
+// is given alone to check -file instead.
+type lintFlag string
+
+func (l *lintFlag) String() string { return string(*l) }
+
+func (l *lintFlag) Set(s string) error {
+	*l = lintFlag(s)
+	return nil
+}
+
+// IsBoolFlag lets -lint be given without a value.
+func (l *lintFlag) IsBoolFlag() bool { return true }
+
+// lintOption defines the -lint flag.
+func lintOption(name, usage string) *lintFlag {
+	l := new(lintFlag)
+	flag.Var(l, name, usage)
+	return l
+}
+
+// lintSeverity ranks a lint issue. Errors make a pack unplayable or
+// ambiguous; warnings are worth a look but the pack still works.
+type lintSeverity int
+
+const (
+	lintWarning lintSeverity = iota
+	lintError
+)
+
+func (s lintSeverity) String() string {
+	if s == lintError {
+		return "error"
+	}
+	return "warning"
+}
+
+// lintIssue is one problem found in a combo pack. Combo is the 1-based
+// position of the combo it concerns, or 0 for the pack as a whole.
+type lintIssue struct {
+	Severity lintSeverity
+	Combo    int
+	Message  string
+}
+
+// maxComboLength is the longest sequence lint accepts without a warning;
+// longer ones no longer fit a typical terminal.
+const maxComboLength = 10
+
+// comboCategories are the "type" values used by the built-in pack.
+var comboCategories = map[string]bool{
+	"Mission Stratagems":      true,
+	"Defensive":               true,
+	"Offensive: Orbital":      true,
+	"Offensive: Eagle":        true,
+	"Supply: Support Weapons": true,
+	"Supply: Backpacks":       true,
+	"Supply: Other":           true,
+}
+
+// comboFields are the keys a combo object may have.
+var comboFields = map[string]bool{"name": true, "sequence": true, "type": true}
+
+// comboIssues checks combos for every problem lint knows about that does not
+// need the raw JSON. validateCombinations rejects packs with any error here.
+func comboIssues(combos []combination) []lintIssue {
+	if len(combos) == 0 {
+		return []lintIssue{{lintError, 0, "no combos"}}
+	}
+	var issues []lintIssue
+	add := func(sev lintSeverity, i int, format string, args ...any) {
+		issues = append(issues, lintIssue{sev, i + 1, fmt.Sprintf(format, args...)})
+	}
+	seen := map[string]bool{}
+	for i, combo := range combos {
+		if strings.TrimSpace(combo.Name) == "" {
+			add(lintError, i, "combo %d has no name", i+1)
+		} else if combo.Name != strings.TrimSpace(combo.Name) {
+			add(lintWarning, i, "combo %q has leading or trailing spaces in its name", combo.Name)
+		}
+		if seen[combo.Name] {
+			add(lintError, i, "duplicate combo name %q", combo.Name)
+		}
+		seen[combo.Name] = true
+		arrows := arrowsOnly(combo.Sequence)
+		if arrows == "" {
+			add(lintError, i, "combo %q has an empty sequence", combo.Name)
+		}
+		for _, char := range arrows {
+			if _, ok := arrowsMap[char]; !ok {
+				add(lintError, i, "combo %q has invalid direction %q", combo.Name, char)
+				break
+			}
+		}
+		if arrows != "" && (strings.HasPrefix(combo.Sequence, string(checkpointMarker)) || strings.HasSuffix(combo.Sequence, string(checkpointMarker)) || strings.Contains(combo.Sequence, ",,")) {
+			add(lintWarning, i, "combo %q has a checkpoint marker at an end or doubled, which does nothing", combo.Name)
+		}
+		if n := len([]rune(arrows)); n > maxComboLength {
+			add(lintWarning, i, "combo %q is %d arrows long; more than %d may not fit the screen", combo.Name, n, maxComboLength)
+		}
+		if combo.Type != "" && !comboCategories[combo.Type] {
+			add(lintWarning, i, "combo %q has unknown type %q", combo.Name, combo.Type)
+		}
+	}
+	return issues
+}
+
+// lintCombos checks the pack at path, "-" for standard input, and prints a
+// report to stdout. It returns the number of errors found.
+func lintCombos(path string) (int, error) {
+	data, err := readComboFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var issues []lintIssue
+	var raw []map[string]json.RawMessage
+	var combos []combination
+	var syntax any
+	if err := json.Unmarshal(data, &syntax); err != nil {
+		issues = append(issues, lintIssue{lintError, 0, "invalid JSON: " + err.Error()})
+	} else if err := json.Unmarshal(data, &raw); err != nil {
+		issues = append(issues, lintIssue{lintError, 0, "expected a JSON array of combo objects"})
+	} else if err := json.Unmarshal(data, &combos); err != nil {
+		issues = append(issues, lintIssue{lintError, 0, "schema: " + err.Error()})
+	} else {
+		for i, obj := range raw {
+			var unknown []string
+			for key := range obj {
+				if !comboFields[key] {
+					unknown = append(unknown, key)
+				}
+			}
+			sort.Strings(unknown)
+			for _, key := range unknown {
+				issues = append(issues, lintIssue{lintWarning, i + 1, fmt.Sprintf("combo %d has unknown field %q", i+1, key)})
+			}
+		}
+		issues = append(issues, comboIssues(combos)...)
+		sort.SliceStable(issues, func(i, j int) bool { return issues[i].Combo < issues[j].Combo })
+	}
+
+	errs, warnings := 0, 0
+	for _, issue := range issues {
+		if issue.Severity == lintError {
+			errs++
+		} else {
+			warnings++
+		}
+		fmt.Printf("%s: %s: %s\n", path, issue.Severity, issue.Message)
+	}
+	fmt.Printf("%s: %d combos, %d errors, %d warnings\n", path, len(raw), errs, warnings)
+	return errs, nil
+}
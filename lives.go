@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// livesLeft counts down the current run's lives with -lives; playMode sets
+// it and clears it when the run ends. dying is set when the last life went
+// on a combo that -finish-on-death lets the player complete.
+var (
+	livesLeft int
+	dying     bool
+)
+
+// loseLife takes a life for a wrong key. It returns true when that was the
+// last one and the combo must end at once; with -finish-on-death it instead
+// marks the player dying and lets the combo go on.
+func loseLife() bool {
+	if livesLeft == 0 {
+		return false
+	}
+	livesLeft--
+	if livesLeft > 0 {
+		return false
+	}
+	if *finishOnDeath {
+		dying = true
+		return false
+	}
+	return true
+}
+
+// deathCredit returns a combo's score once it has been finished on the last
+// life: half of any points earned, while penalties stand in full.
+func deathCredit(score int) int {
+	if score > 0 {
+		return score / 2
+	}
+	return score
+}
+
+// outOfLives reports whether the run has lost all its lives.
+func outOfLives() bool {
+	return *lives > 0 && livesLeft == 0
+}
+
+// livesLines returns the lives left for the header, or nothing without -lives.
+func livesLines() []string {
+	if *lives <= 0 || (livesLeft == 0 && !dying) {
+		return nil
+	}
+	if dying {
+		return []string{"Lives: none left, finish this combo for half credit"}
+	}
+	return []string{fmt.Sprintf("Lives: %s%s", strings.Repeat("♥", livesLeft), strings.Repeat("♡", *lives-livesLeft))}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// numberFormat is how a locale writes numbers: the thousands separator and
+// the decimal mark.
+type numberFormat struct {
+	thousands string
+	decimal   string
+}
+
+// numberFormats are the locales -locale accepts. The empty locale keeps the
+// plain %d and %.2f formatting.
+var numberFormats = map[string]numberFormat{
+	"":   {"", "."},
+	"en": {",", "."},
+	"de": {".", ","},
+	"fr": {" ", ","},
+	"ch": {"'", "."},
+}
+
+// numFormat is the format selected by -locale.
+func numFormat() numberFormat {
+	return numberFormats[strings.ToLower(*locale)]
+}
+
+// fmtInt formats n with the -locale thousands separator.
+func fmtInt(n int) string {
+	s := strconv.Itoa(n)
+	if n < 0 {
+		return "-" + group(s[1:], numFormat().thousands)
+	}
+	return group(s, numFormat().thousands)
+}
+
+// fmtFloat formats f with prec decimals in the -locale style.
+func fmtFloat(f float64, prec int) string {
+	nf := numFormat()
+	s := strconv.FormatFloat(f, 'f', prec, 64)
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	s = sign + group(whole, nf.thousands)
+	if hasFrac {
+		s += nf.decimal + frac
+	}
+	return s
+}
+
+// group inserts sep between every three digits of digits, from the right.
+func group(digits, sep string) string {
+	if sep == "" || len(digits) <= 3 {
+		return digits
+	}
+	var b strings.Builder
+	lead := len(digits) % 3
+	if lead > 0 {
+		b.WriteString(digits[:lead])
+	}
+	for i := lead; i < len(digits); i += 3 {
+		if b.Len() > 0 {
+			b.WriteString(sep)
+		}
+		b.WriteString(digits[i : i+3])
+	}
+	return b.String()
+}
+
+// validLocale reports an error for a -locale value without a number format.
+func validLocale(name string) error {
+	if _, ok := numberFormats[strings.ToLower(name)]; !ok {
+		return fmt.Errorf("unknown locale %q: use en, de, fr or ch", name)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/nsf/termbox-go"
+)
+
+// macro is a recorded play session: the random seed and menu choice needed to
+// reproduce the same combos, and every key press with its timing.
+type macro struct {
+	Seed    int64        `json:"seed"`
+	Mode    string       `json:"mode"`
+	Events  []macroEvent `json:"events"`
+	Splits  []int64      `json:"splits_ms,omitempty"` // when each combo was cleared, for -ghost
+	Derived *derivedRun  `json:"derived,omitempty"`
+}
+
+// derivedRun is what a run took from the player's saved stats rather than
+// from the seed: practice mode's focus direction and the welcome-back ease.
+// Replays and ghosts reuse it, so they deal the same arrows after the stats
+// have moved on.
+type derivedRun struct {
+	Focus string `json:"focus,omitempty"`
+	Ease  int    `json:"ease,omitempty"`
+}
+
+// replaying is set while a -play-macro replay runs. A replay saves nothing:
+// its stats, scores and unlocks were earned when it was recorded.
+var replaying bool
+
+// runDerived is what the current run derived from stats, saved with -record.
+// replayDerived, when set, is the replayed or raced macro's, used instead.
+var (
+	runDerived    derivedRun
+	replayDerived *derivedRun
+)
+
+// needsDerived reports whether a macro of mode can't be replayed without
+// its derivedRun.
+func needsDerived(mode string) bool {
+	return mode == "2" || mode == "5"
+}
+
+// macroEvent is one recorded key press, AtMS milliseconds after play started.
+type macroEvent struct {
+	AtMS int64       `json:"at_ms"`
+	Key  termbox.Key `json:"key,omitempty"`
+	Ch   rune        `json:"ch,omitempty"`
+}
+
+// loadMacro reads a macro written by -record.
+func loadMacro(filename string) (*macro, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var m macro
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// saveMacro writes m to filename.
+func saveMacro(filename string, m *macro) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filename, data)
+}
+
+// macroRecorder is an InputSource that passes events through from another
+// source while noting the time and key of every key press. Recording only
+// appends to a slice; the file is written once play is over.
+type macroRecorder struct {
+	events chan termbox.Event
+	start  time.Time
+
+	mu       sync.Mutex
+	recorded []macroEvent
+}
+
+// newMacroRecorder starts recording the key presses delivered by src.
+func newMacroRecorder(src InputSource) *macroRecorder {
+	r := &macroRecorder{events: make(chan termbox.Event), start: time.Now()}
+	go func() {
+		for ev := range src.Events() {
+			if ev.Type == termbox.EventKey {
+				r.mu.Lock()
+				r.recorded = append(r.recorded, macroEvent{
+					AtMS: time.Since(r.start).Milliseconds(),
+					Key:  ev.Key,
+					Ch:   ev.Ch,
+				})
+				r.mu.Unlock()
+			}
+			r.events <- ev
+		}
+	}()
+	return r
+}
+
+// Events returns the passed-through event channel.
+func (r *macroRecorder) Events() <-chan termbox.Event {
+	return r.events
+}
+
+// Recorded returns a copy of the key presses recorded so far.
+func (r *macroRecorder) Recorded() []macroEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]macroEvent(nil), r.recorded...)
+}
+
+// ScriptedInput is an InputSource that replays a fixed list of key presses,
+// each at its recorded offset from when the ScriptedInput was created, scaled
+// by the playback speed. Quit keys pressed on the live source still get
+// through, so a playback can be aborted.
+type ScriptedInput struct {
+	events chan termbox.Event
+}
+
+// NewScriptedInput starts replaying script at the given speed, where 1 keeps
+// the recorded timing, 2 halves every gap and 0.5 doubles it. live may be nil.
+func NewScriptedInput(script []macroEvent, speed float64, live InputSource) *ScriptedInput {
+	s := &ScriptedInput{events: make(chan termbox.Event)}
+	start := time.Now()
+	go func() {
+		for _, me := range script {
+			at := time.Duration(float64(me.AtMS) / speed * float64(time.Millisecond))
+			time.Sleep(time.Until(start.Add(at)))
+			s.events <- termbox.Event{Type: termbox.EventKey, Key: me.Key, Ch: me.Ch}
+		}
+	}()
+	if live != nil {
+		go func() {
+			for ev := range live.Events() {
+				if ev.Type == termbox.EventError || (ev.Type == termbox.EventKey && isQuitKey(ev)) {
+					s.events <- ev
+				}
+			}
+		}()
+	}
+	return s
+}
+
+// Events returns the scripted event channel.
+func (s *ScriptedInput) Events() <-chan termbox.Event {
+	return s.events
+}
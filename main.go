@@ -2,13 +2,23 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"embed"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"math"
 	"math/rand"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"text/tabwriter"
 	"time"
+	"unicode/utf8"
 
 	"github.com/nsf/termbox-go"
 )
@@ -16,305 +26,2137 @@ import (
 //go:embed stratagems.json
 var embeddedFiles embed.FS
 
+// defaultComboFile is the default -file. Only it falls back to the embedded
+// copy when absent; any other missing pack is an error.
+const defaultComboFile = "stratagems.json"
+
 // combination represents a combo loaded from JSON.
 type combination struct {
 	Name     string `json:"name"`
 	Sequence string `json:"sequence"`
+	Type     string `json:"type,omitempty"` // category, such as "Offensive: Eagle"
 }
 
-// Arrow holds the ASCII art and the expected termbox key for detection.
+// Arrow holds the direction letter, the ASCII art and the expected termbox key for detection.
+// When a direction is remapped to a character key, Ch is set and Key is ignored.
 type Arrow struct {
+	Dir rune
 	Art string
 	Key termbox.Key
+	Ch  rune
+	// Checkpoint is set when a checkpointMarker follows the arrow in its
+	// combo, so -strict keeps the progress up to it after a wrong key.
+	Checkpoint bool
+}
+
+// checkpointMarker separates a combo's sequence into checkpoints.
+const checkpointMarker = ','
+
+// checkpointBefore reports whether a checkpoint lies just before input
+// position i of order, the combo in input order.
+func checkpointBefore(order []Arrow, i int) bool {
+	if i <= 0 || i >= len(order) {
+		return false
+	}
+	// Reversed, the arrow displayed before the marker is the later one.
+	if *reverse {
+		return order[i].Checkpoint
+	}
+	return order[i-1].Checkpoint
+}
+
+// strictReset returns the input position a wrong key at position i sends
+// the player back to with -strict: the last checkpoint before i, else the
+// start of the combo. It also returns the points earned since then, which
+// are taken back so they aren't scored twice.
+func strictReset(order []Arrow, i int) (int, int) {
+	back := i
+	for back > 0 && !checkpointBefore(order, back) {
+		back--
+	}
+	points := 0
+	for _, arrow := range order[back:i] {
+		points += arrowPoints(arrow)
+	}
+	return back, points
+}
+
+// matches reports whether ev is the key bound to this arrow.
+func (a Arrow) matches(ev termbox.Event) bool {
+	if a.Ch != 0 {
+		return ev.Ch == a.Ch
+	}
+	return ev.Ch == 0 && ev.Key == a.Key
+}
+
+// poolProgress tracks how many combos of the loaded pool a run has cleared.
+// It is only shown when a mode plays the entire pool; a zero Total hides it.
+type poolProgress struct {
+	Cleared int
+	Total   int
+}
+
+// outcome is what happened to a single combo of a run.
+type outcome int
+
+const (
+	outcomeCleared outcome = iota // entered completely
+	outcomeFailed                 // abandoned or timed out part way through
+	outcomeSkipped                // never attempted
+)
+
+// String returns the outcome's name as used in JSON output.
+func (o outcome) String() string {
+	switch o {
+	case outcomeCleared:
+		return "cleared"
+	case outcomeFailed:
+		return "failed"
+	default:
+		return "skipped"
+	}
+}
+
+// MarshalText encodes an outcome by name.
+func (o outcome) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+// runResult summarises a finished run.
+type runResult struct {
+	User         string        `json:"user"`
+	Mode         string        `json:"mode"`
+	Score        int           `json:"score"`
+	Elapsed      float64       `json:"elapsed_seconds"`
+	Outcomes     []outcome     `json:"outcomes"`
+	Repeated     bool          `json:"repeated,omitempty"` // combos were repeated to reach -count
+	NoQuit       bool          `json:"no_quit,omitempty"`  // -no-quit was enforced
+	Combos       []comboRecord `json:"combos,omitempty"`   // timed mode's cleared combos, in play order
+	Perfect      int           `json:"perfect,omitempty"`  // timed combos that earned the top bonus
+	Mistakes     int           `json:"mistakes"`
+	Skips        int           `json:"skips,omitempty"`        // combos skipped with Tab
+	SkipPoints   int           `json:"skip_points,omitempty"`  // points those skips cost, after -combo-floor
+	Efficiency   float64       `json:"efficiency"`             // share of key presses that were correct arrows
+	Ladder       *ladderResult `json:"ladder,omitempty"`       // how far a speed ladder run got
+	Achievements []string      `json:"achievements,omitempty"` // names of the achievements this run unlocked
+}
+
+// comboRecord is timed mode's breakdown of one cleared combo.
+type comboRecord struct {
+	Name     string  `json:"name"`
+	Seconds  float64 `json:"seconds"`
+	Bonus    int     `json:"bonus"`
+	Mistakes int     `json:"mistakes"`
+	Arrows   int     `json:"arrows"`
+}
+
+// efficiency is the share of key presses that were correct arrows, given
+// the correct and wrong presses.
+func efficiency(correct, wrong int) float64 {
+	if correct+wrong == 0 {
+		return 1
+	}
+	return float64(correct) / float64(correct+wrong)
+}
+
+// newRunResult builds the result of a run that started at start and planned
+// to play planned combos. Combos beyond those in outcomes count as skipped.
+func newRunResult(score int, start time.Time, outcomes []outcome, planned int) runResult {
+	for len(outcomes) < planned {
+		outcomes = append(outcomes, outcomeSkipped)
+	}
+	return runResult{Score: score, Elapsed: time.Since(start).Seconds(), Outcomes: outcomes}
 }
 
 // Map runes to Arrow objects.
 var arrowsMap = map[rune]Arrow{
 	'U': {
+		Dir: 'U',
 		Art: "   ██   \n ██████ \n████████\n   ██   \n   ██   ",
 		Key: termbox.KeyArrowUp,
 	},
 	'D': {
+		Dir: 'D',
 		Art: "   ██   \n   ██   \n████████\n ██████ \n   ██   ",
 		Key: termbox.KeyArrowDown,
 	},
 	'L': {
+		Dir: 'L',
 		Art: "    ███   \n  █████   \n██████████\n  █████   \n    ███   ",
 		Key: termbox.KeyArrowLeft,
 	},
 	'R': {
+		Dir: 'R',
 		Art: "   ███    \n   █████  \n██████████\n   █████  \n   ███    ",
 		Key: termbox.KeyArrowRight,
 	},
 }
 
-// loadCombinations attempts to load the combinations from a local file.
-// If the local file is not found, it falls back to the embedded JSON.
+// Command-line flags.
+var (
+	quiet            = flag.Bool("quiet", false, "suppress informational messages; prompts, the game display and results are still shown")
+	noSplash         = flag.Bool("no-splash", false, "skip the splash screen shown at launch")
+	userName         = flag.String("user", "", "player name; skips the username prompt")
+	askUser          = flag.Bool("ask-user", false, "always prompt for the username, offering the detected name as the default")
+	reverse          = flag.Bool("reverse", false, "enter every combo in reverse order, last arrow first")
+	remap            = flag.String("remap", "", "bind directions to other keys, e.g. \"U=DOWN,D=UP\" or \"U=w,D=s,L=a,R=d\"")
+	burst            = flag.Duration("burst-window", 15*time.Millisecond, "drop key presses arriving closer together than this (pasted input); 0 disables")
+	hintWait         = flag.Duration("hint-after", 0, "in untimed modes, underline the next arrow after this long without a correct key, at a small penalty; ignored with -strict and -no-quit; 0 disables")
+	bossRun          = flag.Bool("boss", false, "finish every run with a longer, high-value boss combo")
+	record           = flag.String("record", "", "record this session's key presses and timing to a macro file")
+	playback         = flag.String("play-macro", "", "replay a macro recorded with -record instead of reading the keyboard")
+	saveEvery        = flag.Int("autosave-combos", 10, "in endless mode, checkpoint the run every N combos; 0 disables")
+	saveInterval     = flag.Duration("autosave-interval", time.Minute, "in endless mode, checkpoint the run at least this often; 0 disables")
+	weights          = flag.String("weights", "", "per-direction scoring weights, e.g. \"U=1,D=1,L=1.5,R=1.5\" or a JSON file mapping U/D/L/R to weights")
+	rtl              = flag.Bool("rtl", false, "draw combos right-aligned and read right to left; input order is unchanged")
+	comboFile        = flag.String("file", defaultComboFile, "combo file to play; the built-in stratagems are used when the default file is absent")
+	generate         = flag.Int("generate", 0, "write N random combos to -out as a starting point for a custom pack, then exit")
+	genOut           = flag.String("out", "custom.json", "output file for -generate")
+	overwrite        = flag.Bool("overwrite", false, "let -generate replace an existing -out file")
+	genMin           = flag.Int("min-len", 3, "shortest sequence -generate creates")
+	genMax           = flag.Int("max-len", 8, "longest sequence -generate creates")
+	demoSpeed        = flag.Float64("demo-speed", 1, "playback speed for -play-macro: 2 replays twice as fast, 0.5 at half speed")
+	noQuit           = flag.Bool("no-quit", false, "competition rules: Esc and q no longer abort a run and Tab no longer skips a combo, only Ctrl+C leaves one")
+	shake            = flag.Bool("shake", false, "silently shake the playfield on a wrong key")
+	webhook          = flag.String("webhook", "", "POST each run's result as JSON to this URL (best effort)")
+	count            = flag.Int("count", 10, "number of combos per run")
+	repeatPool       = flag.Bool("repeat", false, "when -count exceeds the combo pool, repeat combos (reshuffled each pass) instead of playing each once")
+	webAddr          = flag.String("web", "", "serve a browser version of the game on this address, e.g. :8080, instead of playing in the terminal")
+	logFile          = flag.String("log", "", "write a per-combo log of the run to this JSON Lines file")
+	diffSpec         = flag.String("diff", "", "compare two run logs written by -log, given as a.jsonl,b.jsonl, then exit")
+	settle           = flag.Duration("settle", 0, "ignore key presses for this long after each new combo appears, e.g. 250ms, so it can register first")
+	rest             = flag.Duration("rest", 0, "in endless mode, count down this long between combos for a steady pace, e.g. 1s; 0 disables")
+	order            = flag.String("order", "shuffle", "order of the selected combos: shuffle, or ramp to play them easiest to hardest")
+	revenge          = flag.Bool("revenge", false, "build JSON and timed runs mostly from the combos you have failed most, padded with random ones")
+	skipPenalty      = flag.Int("skip-penalty", 10, "points lost for skipping a combo with Tab in untimed modes; 0 makes skipping free")
+	inputCheck       = flag.Duration("input-check", 10*time.Second, "if no key at all arrives this long into the first combo, suggest checking that the terminal forwards keys; 0 disables")
+	lint             = lintOption("lint", "check a combo pack for problems and exit, non-zero on errors: -lint=pack.json, or -lint alone for -file (- reads stdin)")
+	ghostFile        = flag.String("ghost", "", "race a run recorded with -record: play its combos live against its progress; use the same flags it was recorded with")
+	locale           = flag.String("locale", "", "number style for the summary: en (1,234.56), de (1.234,56), fr (1 234,56) or ch (1'234.56); plain by default")
+	study            = flag.String("study", "", "write a printable study sheet of -file's combos to this text file, then exit")
+	category         = flag.String("category", "", "with -study or -list, only include combos whose type contains this text, e.g. eagle")
+	drill            = flag.Bool("drill", false, "after the run, drill each arrow you missed again as a single-arrow prompt")
+	initConfig       = flag.Bool("init-config", false, "write "+configFile+" with every flag's default value for editing, then exit; flags given on the command line override it")
+	startMode        = flag.String("mode", "", "start this menu option directly instead of showing the menu, e.g. 3 for timed")
+	showStats        = flag.Bool("stats", false, "print your lifetime stats, including a direction-transition heat map, then exit")
+	lookahead        = flag.Int("lookahead", 0, "show the next N combos dimmed below the current one so you can read ahead")
+	anon             = flag.Bool("anon", false, "store this run on the leaderboard under a pseudonymous handle keyed by this install's anon.key, and show only handles with -leaderboard")
+	leaderboard      = flag.Bool("leaderboard", false, "print the local leaderboard, then exit")
+	briefing         = flag.String("briefing", "", "before JSON and timed runs, list the combos to come: names, or full for names and sequences")
+	timeLimit        = flag.Duration("time", 30*time.Second, "overall time limit for timed mode")
+	randomLen        = flag.Int("length", 6, "number of arrows in each random and practice mode sequence")
+	bell             = flag.Bool("bell", false, "ring the terminal bell on each wrong key; m mutes it mid-run")
+	progressSound    = flag.Bool("progress-sound", false, "sound a soft tick on each correct arrow, rising in pitch through the combo where the terminal can")
+	hotSeat          = flag.String("hot-seat", "", "comma-separated players who take turns at the chosen mode on this keyboard, e.g. alice,bob, with a ready check before each turn")
+	find             = flag.String("find", "", "list the combos whose sequence matches or contains this arrow pattern, e.g. UDLR, then exit")
+	comboFloor       = flag.Int("combo-floor", 0, "the most points a single combo's penalties can cost, so one combo you can't get doesn't sink the run; 0 for no limit")
+	strict           = flag.Bool("strict", false, "a wrong key sends you back to the start of the combo, or to its last checkpoint: a ',' in the sequence, e.g. UDLR,RLDU")
+	breakEvery       = flag.Int("break-every", 0, "in JSON, random, practice and endless runs, suggest a short break after every N combos; 0 disables")
+	list             = flag.Bool("list", false, "browse -file's combos in the terminal, previewing each one's arrows; Enter drills the selected combo")
+	lives            = flag.Int("lives", 0, "end the run once this many wrong keys have been pressed; 0 for unlimited")
+	finishOnDeath    = flag.Bool("finish-on-death", false, "with -lives, let the combo that costs the last life be finished for half credit instead of ending it at once")
+	trailLen         = flag.Int("trail", 0, "show a ✓/✗ trail of your last N arrow key presses beneath the combo; 0 hides it")
+	fairClock        = flag.Bool("fair-clock", false, "in timed modes, give back the time spent drawing the screen, so both clocks measure only your own time")
+	showAchievements = flag.Bool("achievements", false, "list every achievement and which ones you have unlocked, then exit")
+)
+
+// console receives everything meant for the player's eyes: menus, prompts,
+// errors and the in-game display. Stdout is reserved for results that scripts
+// may want to capture, such as the final scorecard.
+var console io.Writer = os.Stderr
+
+// screen is where the play loops draw the in-game display.
+var screen Renderer = termboxRenderer{}
+
+// input is where the play loops read key events from.
+var input InputSource = termboxInput{}
+
+// arrowWeights scales the points awarded for each correct arrow, keyed by the
+// arrow's direction. Directions without an entry are worth the default weight of 1.
+var arrowWeights = map[rune]float64{}
+
+// arrowKeyNames maps the names accepted by -remap to termbox arrow keys.
+var arrowKeyNames = map[string]termbox.Key{
+	"UP":    termbox.KeyArrowUp,
+	"DOWN":  termbox.KeyArrowDown,
+	"LEFT":  termbox.KeyArrowLeft,
+	"RIGHT": termbox.KeyArrowRight,
+}
+
+// shakeDuration is how long the playfield stays offset after a wrong key with -shake.
+const shakeDuration = 80 * time.Millisecond
+
+// hintPenalty is the score deducted each time a hesitation hint is shown.
+const hintPenalty = 5
+
+// Boss combo tuning for -boss: random runs get a boss of bossLength arrows,
+// JSON runs face the hardest combo in the pool. Clearing it earns bossBonus.
+const (
+	bossLength = 9
+	bossBonus  = 250
+)
+
+// splashTimeout is how long the splash screen stays up without a key press.
+const splashTimeout = 3 * time.Second
+
+// splashArt is the banner drawn by showSplash.
+var splashArt = []string{
+	"█   █ █████ █     █     ████  ███ █   █ █████ ████   ████",
+	"█   █ █     █     █     █   █  █  █   █ █     █   █ █    ",
+	"█████ ████  █     █     █   █  █  █   █ ████  ████   ███ ",
+	"█   █ █     █     █     █   █  █   █ █  █     █  █      █",
+	"█   █ █████ █████ █████ ████  ███   █   █████ █   █ ████ ",
+}
+
+// loadPack loads -file for a run and notes its names in packNames.
+func loadPack() ([]combination, error) {
+	combos, err := loadCombinations(*comboFile)
+	if err != nil {
+		return nil, err
+	}
+	packNames = map[string]bool{}
+	for _, combo := range combos {
+		packNames[combo.Name] = true
+	}
+	return combos, nil
+}
+
+// loadCombinations loads the combinations from filename, or from standard
+// input for "-". Only the default stratagems.json falls back to the
+// embedded pack when absent; any other missing file is an error.
 func loadCombinations(filename string) ([]combination, error) {
+	data, err := readComboFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var combos []combination
+	err = json.Unmarshal(data, &combos)
+	if err != nil {
+		return nil, err
+	}
+	return combos, nil
+}
+
+// readComboFile returns the contents of a combo file, or of the embedded
+// pack when it is the default file and does not exist, with any byte order
+// mark removed. A filename of
+// "-" reads standard input.
+func readComboFile(filename string) ([]byte, error) {
 	var data []byte
 	var err error
-	if fileExists(filename) {
+	if filename == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else if filename == defaultComboFile && !fileExists(filename) {
+		data, err = embeddedFiles.ReadFile(defaultComboFile)
+	} else {
 		data, err = os.ReadFile(filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Editors on Windows often save with a byte order mark, which JSON
+	// rejects with an unhelpful "invalid character" error.
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(data) {
+		return nil, fmt.Errorf("%s is not valid UTF-8; re-save it with UTF-8 encoding", filename)
+	}
+	return data, nil
+}
+
+// validateCombinations checks that combos is a usable pack: at least one
+// combo, every name present and unique, and every sequence non-empty and made
+// only of the letters U, D, L and R.
+func validateCombinations(combos []combination) error {
+	for _, issue := range comboIssues(combos) {
+		if issue.Severity == lintError {
+			return errors.New(issue.Message)
+		}
+	}
+	return nil
+}
+
+// generateCombos creates n combos named "Combo-01", "Combo-02", ... with random
+// sequences between minLen and maxLen arrows long.
+func generateCombos(n, minLen, maxLen int) []combination {
+	width := len(strconv.Itoa(n))
+	if width < 2 {
+		width = 2
+	}
+	dirs := []rune{'U', 'D', 'L', 'R'}
+	combos := make([]combination, n)
+	for i := range combos {
+		seq := make([]rune, minLen+rng.Intn(maxLen-minLen+1))
+		for j := range seq {
+			seq[j] = dirs[rng.Intn(len(dirs))]
+		}
+		combos[i] = combination{
+			Name:     fmt.Sprintf("Combo-%0*d", width, i+1),
+			Sequence: string(seq),
+		}
+	}
+	return combos
+}
+
+// writeGeneratedCombos generates n combos and saves them to filename as a
+// pack that loadCombinations can read. An existing file is only replaced
+// with -overwrite, so a hand-written pack isn't lost to a stray -generate.
+func writeGeneratedCombos(filename string, n, minLen, maxLen int) error {
+	if minLen < 1 || maxLen < minLen {
+		return fmt.Errorf("invalid length range %d-%d", minLen, maxLen)
+	}
+	if _, err := os.Stat(filename); err == nil && !*overwrite {
+		return fmt.Errorf("%s already exists; pass -overwrite to replace it", filename)
+	}
+	combos := generateCombos(n, minLen, maxLen)
+	if err := validateCombinations(combos); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(combos, "", "    ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filename, append(data, '\n'))
+}
+
+// loadWeights parses the -weights value into arrowWeights. The value is either a
+// path to a JSON object such as {"U": 1, "L": 1.5} or an inline list like "U=1,L=1.5".
+func loadWeights(value string) error {
+	raw := map[string]float64{}
+	if strings.HasSuffix(strings.ToLower(value), ".json") {
+		data, err := os.ReadFile(value)
 		if err != nil {
-			return nil, err
+			return err
+		}
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return err
 		}
 	} else {
-		data, err = embeddedFiles.ReadFile("stratagems.json")
-		if err != nil {
-			return nil, err
+		for _, pair := range strings.Split(value, ",") {
+			dir, w, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok {
+				return fmt.Errorf("expected DIRECTION=WEIGHT, got %q", pair)
+			}
+			f, err := strconv.ParseFloat(strings.TrimSpace(w), 64)
+			if err != nil {
+				return fmt.Errorf("weight for %s: %w", dir, err)
+			}
+			raw[strings.TrimSpace(dir)] = f
 		}
 	}
-	var combos []combination
-	err = json.Unmarshal(data, &combos)
+	for dir, w := range raw {
+		runes := []rune(strings.ToUpper(dir))
+		if len(runes) != 1 {
+			return fmt.Errorf("unknown direction %q", dir)
+		}
+		arrow, ok := arrowsMap[runes[0]]
+		if !ok {
+			return fmt.Errorf("unknown direction %q", dir)
+		}
+		if w < 0 {
+			return fmt.Errorf("weight for %s must not be negative", dir)
+		}
+		arrowWeights[arrow.Dir] = w
+	}
+	return nil
+}
+
+// applyRemap rebinds the keys in arrowsMap from a -remap value such as
+// "U=DOWN,D=UP" or "U=w,D=s,L=a,R=d". Each target is an arrow key name or a
+// single character; the resulting layout must bind every direction to a distinct key.
+func applyRemap(value string) error {
+	for _, pair := range strings.Split(value, ",") {
+		dir, target, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return fmt.Errorf("expected DIRECTION=KEY, got %q", pair)
+		}
+		runes := []rune(strings.ToUpper(strings.TrimSpace(dir)))
+		if len(runes) != 1 {
+			return fmt.Errorf("unknown direction %q", dir)
+		}
+		arrow, ok := arrowsMap[runes[0]]
+		if !ok {
+			return fmt.Errorf("unknown direction %q", dir)
+		}
+		target = strings.TrimSpace(target)
+		if key, ok := arrowKeyNames[strings.ToUpper(target)]; ok {
+			arrow.Key, arrow.Ch = key, 0
+		} else if t := []rune(target); len(t) == 1 && t[0] != 'q' && t[0] != ' ' {
+			arrow.Key, arrow.Ch = 0, t[0]
+		} else {
+			return fmt.Errorf("cannot bind %s to %q", dir, target)
+		}
+		arrowsMap[arrow.Dir] = arrow
+	}
+
+	seen := map[Arrow]rune{}
+	for dir, arrow := range arrowsMap {
+		bound := Arrow{Key: arrow.Key, Ch: arrow.Ch}
+		if other, ok := seen[bound]; ok {
+			return fmt.Errorf("%c and %c are bound to the same key", other, dir)
+		}
+		seen[bound] = dir
+	}
+	return nil
+}
+
+// keyName describes the key an arrow is bound to, for on-screen hints.
+func keyName(arrow Arrow) string {
+	if arrow.Ch != 0 {
+		return string(arrow.Ch)
+	}
+	for name, key := range arrowKeyNames {
+		if key == arrow.Key {
+			return name
+		}
+	}
+	return "?"
+}
+
+// inputOrder returns the order in which the arrows of sequence must be entered,
+// which is reversed when -reverse is set. The displayed sequence is never changed.
+func inputOrder(sequence []Arrow) []Arrow {
+	if !*reverse {
+		return sequence
+	}
+	expected := make([]Arrow, len(sequence))
+	for i, arrow := range sequence {
+		expected[len(sequence)-1-i] = arrow
+	}
+	return expected
+}
+
+// isQuitKey reports whether ev asks to abort the run. With -no-quit only
+// Ctrl+C does, as an emergency exit.
+func isQuitKey(ev termbox.Event) bool {
+	if *noQuit {
+		return ev.Key == termbox.KeyCtrlC
+	}
+	return ev.Key == termbox.KeyEsc || ev.Ch == 'q' || ev.Key == termbox.KeyCtrlC
+}
+
+// isSkipKey reports whether ev skips the current combo. Under -no-quit only
+// Ctrl+C may leave a combo, so Tab does nothing.
+func isSkipKey(ev termbox.Event) bool {
+	return ev.Key == termbox.KeyTab && !*noQuit
+}
+
+// isBlockedQuit reports whether ev is a quit or skip key that -no-quit has
+// disabled.
+func isBlockedQuit(ev termbox.Event) bool {
+	return *noQuit && (ev.Key == termbox.KeyEsc || ev.Ch == 'q' || ev.Key == termbox.KeyTab)
+}
+
+// blockedQuitMessage is shown when a disabled quit key is pressed.
+const blockedQuitMessage = "Quitting and skipping are disabled under -no-quit (Ctrl+C aborts)."
+
+// displayIndex maps the position of the i-th arrow to be entered onto its
+// position in the displayed sequence of length n.
+func displayIndex(n, i int) int {
+	if *reverse {
+		return n - 1 - i
+	}
+	return i
+}
+
+// penalize returns a combo's score after deducting points, held at
+// -combo-floor when set.
+func penalize(score, points int) int {
+	score -= points
+	if *comboFloor > 0 && score < -*comboFloor {
+		score = -*comboFloor
+	}
+	return score
+}
+
+// arrowPoints returns the points awarded for entering arrow correctly.
+func arrowPoints(arrow Arrow) int {
+	w, ok := arrowWeights[arrow.Dir]
+	if !ok {
+		w = 1
+	}
+	return int(math.Round(20 * w))
+}
+
+// writeFileAtomic writes data to a temporary file next to filename and renames
+// it into place, so a crash mid-write never leaves a truncated file behind.
+func writeFileAtomic(filename string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".tmp*")
 	if err != nil {
-		return nil, err
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// fileExists checks if a file exists and is not a directory.
+func fileExists(filename string) bool {
+	info, err := os.Stat(filename)
+	return err == nil && !info.IsDir()
+}
+
+func main() {
+	flag.Parse()
+	// No flag takes a positional argument. -lint in particular only takes
+	// its pack as -lint=pack.json; with a space the pack would land here.
+	if flag.NArg() > 0 {
+		if *lint != "" {
+			fmt.Fprintf(console, "Unexpected argument %q: give -lint its pack as -lint=%s\n", flag.Arg(0), flag.Arg(0))
+		} else {
+			fmt.Fprintf(console, "Unexpected argument %q\n", flag.Arg(0))
+		}
+		os.Exit(1)
+	}
+	if *initConfig {
+		if err := writeDefaultConfig(); err != nil {
+			fmt.Fprintln(console, "Error writing config:", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(console, "Wrote %s\n", configFile)
+		return
+	}
+	if err := loadConfig(); err != nil {
+		fmt.Fprintln(console, "Error loading config:", err)
+		os.Exit(1)
+	}
+	loadPrefs()
+	if err := validLocale(*locale); err != nil {
+		fmt.Fprintln(console, "Invalid -locale:", err)
+		os.Exit(1)
+	}
+	if *trailLen < 0 {
+		fmt.Fprintln(console, "Invalid -trail: must not be negative")
+		os.Exit(1)
+	}
+	if *lives < 0 {
+		fmt.Fprintln(console, "Invalid -lives: must not be negative")
+		os.Exit(1)
+	}
+	if *breakEvery < 0 {
+		fmt.Fprintln(console, "Invalid -break-every: must not be negative")
+		os.Exit(1)
+	}
+	if *comboFloor < 0 {
+		fmt.Fprintln(console, "Invalid -combo-floor: must not be negative")
+		os.Exit(1)
+	}
+	if *skipPenalty < 0 {
+		fmt.Fprintln(console, "Invalid -skip-penalty: must not be negative")
+		os.Exit(1)
+	}
+	if *briefing != "" && *briefing != "names" && *briefing != "full" {
+		fmt.Fprintf(console, "Invalid -briefing %q: must be names or full\n", *briefing)
+		os.Exit(1)
+	}
+	if *count < 1 {
+		fmt.Fprintln(console, "Invalid -count: must be at least 1")
+		os.Exit(1)
+	}
+	if *randomLen < 1 {
+		fmt.Fprintln(console, "Invalid -length: must be at least 1")
+		os.Exit(1)
+	}
+	if *timeLimit <= 0 {
+		fmt.Fprintln(console, "Invalid -time: must be greater than 0")
+		os.Exit(1)
+	}
+	if *hotSeat != "" && (*playback != "" || *record != "" || *ghostFile != "") {
+		fmt.Fprintln(console, "-hot-seat can't be combined with -play-macro, -record or -ghost")
+		os.Exit(1)
+	}
+	// A run log has no player field, so turns would run together.
+	if *hotSeat != "" && *logFile != "" {
+		fmt.Fprintln(console, "-hot-seat can't be combined with -log")
+		os.Exit(1)
+	}
+	if *revenge && (*playback != "" || *record != "" || *ghostFile != "") {
+		fmt.Fprintln(console, "-revenge picks combos from your saved stats, so it can't be combined with -play-macro, -record or -ghost")
+		os.Exit(1)
+	}
+	if *order != "shuffle" && *order != "ramp" {
+		fmt.Fprintf(console, "Invalid -order %q: must be shuffle or ramp\n", *order)
+		os.Exit(1)
+	}
+	seed := time.Now().UnixNano()
+	var replay *macro
+	var ghostMode string
+	if *playback != "" {
+		if *demoSpeed <= 0 {
+			fmt.Fprintln(console, "Invalid -demo-speed: must be greater than 0")
+			os.Exit(1)
+		}
+		m, err := loadMacro(*playback)
+		if err != nil {
+			fmt.Fprintln(console, "Error loading macro:", err)
+			os.Exit(1)
+		}
+		if m.Derived == nil && needsDerived(m.Mode) {
+			fmt.Fprintln(console, "Error loading macro: it predates recorded ease and focus; record it again")
+			os.Exit(1)
+		}
+		replay, seed = m, m.Seed
+		replaying, replayDerived = true, m.Derived
+	} else if *ghostFile != "" {
+		m, err := loadMacro(*ghostFile)
+		if err != nil {
+			fmt.Fprintln(console, "Error loading ghost:", err)
+			os.Exit(1)
+		}
+		if m.Derived == nil && needsDerived(m.Mode) {
+			fmt.Fprintln(console, "Error loading ghost: it predates recorded ease and focus; record it again")
+			os.Exit(1)
+		}
+		replayDerived = m.Derived
+		if ghost, err = newGhostRace(*ghostFile, m); err != nil {
+			fmt.Fprintln(console, "Error loading ghost:", err)
+			os.Exit(1)
+		}
+		// The ghost's seed deals the same combos it played.
+		seed = m.Seed
+		ghostMode = m.Mode
+	}
+	rng = rand.New(rand.NewSource(seed))
+
+	if *weights != "" {
+		if err := loadWeights(*weights); err != nil {
+			fmt.Fprintln(console, "Invalid -weights:", err)
+			os.Exit(1)
+		}
+	}
+
+	if *remap != "" {
+		if err := applyRemap(*remap); err != nil {
+			fmt.Fprintln(console, "Invalid -remap:", err)
+			os.Exit(1)
+		}
+	}
+
+	if *generate > 0 {
+		if err := writeGeneratedCombos(*genOut, *generate, *genMin, *genMax); err != nil {
+			fmt.Fprintln(console, "Error generating combos:", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(console, "Wrote %d combos to %s\n", *generate, *genOut)
+		return
+	}
+	// Checked before the one-shot commands too, so a mistyped -file can't
+	// quietly fall back to the built-in pack.
+	if *comboFile != defaultComboFile && *comboFile != "-" && !fileExists(*comboFile) {
+		fmt.Fprintf(console, "Combo file %s not found\n", *comboFile)
+		os.Exit(1)
+	}
+	if *lint != "" {
+		path := string(*lint)
+		if path == "true" {
+			path = *comboFile
+		}
+		errs, err := lintCombos(path)
+		if err != nil {
+			fmt.Fprintln(console, "Error linting combos:", err)
+			os.Exit(1)
+		}
+		if errs > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+	if *find != "" {
+		pattern := normalizePattern(*find)
+		if pattern == "" || strings.Trim(pattern, "UDLR") != "" {
+			fmt.Fprintf(console, "Invalid -find %q: use only U, D, L and R\n", *find)
+			os.Exit(1)
+		}
+		combos, err := loadCombinations(*comboFile)
+		if err != nil {
+			fmt.Fprintf(console, "Error loading combinations: %s\n", err)
+			os.Exit(1)
+		}
+		printFound(combos, pattern)
+		return
+	}
+	if *study != "" {
+		combos, err := loadCombinations(*comboFile)
+		if err == nil {
+			err = writeStudySheet(*study, filterCategory(combos, *category))
+		}
+		if err != nil {
+			fmt.Fprintln(console, "Error writing study sheet:", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(console, "Wrote study sheet to %s\n", *study)
+		return
+	}
+	if *leaderboard {
+		if err := printLeaderboard(os.Stdout); err != nil {
+			fmt.Fprintln(console, "Error reading scores:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if *showAchievements {
+		if err := printAchievements(os.Stdout, resolveUsername()); err != nil {
+			fmt.Fprintln(console, "Error reading achievements:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if *showStats {
+		if err := printStats(os.Stdout, resolveUsername()); err != nil {
+			fmt.Fprintln(console, "Error reading stats:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if *diffSpec != "" {
+		if err := runDiff(*diffSpec); err != nil {
+			fmt.Fprintln(console, "Error comparing runs:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if *webAddr != "" {
+		if err := serveWeb(*webAddr, resolveUsername()); err != nil {
+			fmt.Fprintln(console, "Web UI failed:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	input = newBurstInput(termboxInput{}, *burst)
+
+	if *list {
+		combos, err := loadCombinations(*comboFile)
+		if err == nil {
+			err = browseCombos(filterCategory(combos, *category))
+		}
+		if err != nil {
+			fmt.Fprintln(console, "Error listing combos:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if !*noSplash {
+		showSplash(splashTimeout)
+	}
+
+	players := hotSeatPlayers(*hotSeat)
+	var username string
+	if len(players) == 0 {
+		username = resolveUsername()
+		showRecordsBanner(username)
+	}
+	offerCheckpoint()
+
+	var choice string
+	if replay != nil {
+		choice = replay.Mode
+	} else if ghost != nil {
+		choice = ghostMode
+	} else if *startMode != "" {
+		choice = *startMode
+	} else {
+		// Show options.
+		fmt.Fprintln(console, "Choose an option:")
+		fmt.Fprintf(console, "1: JSON Combos (%d random combos from file)\n", modeCount("json"))
+		fmt.Fprintf(console, "2: Random Combos (%d random sequences of %d arrows)\n", modeCount("random"), modeLength("random"))
+		fmt.Fprintf(console, "3: Timed JSON Combos (%.0f seconds to finish %d random combos)\n", modeTime("timed").Seconds(), modeCount("timed"))
+		fmt.Fprintln(console, "4: Endless JSON Combos (play until you quit)")
+		fmt.Fprintf(console, "5: Weak Direction Practice (%d random sequences favouring your least accurate direction)\n", modeCount("practice"))
+		fmt.Fprintln(console, "6: Gauntlet (every combo in the file back to back as one timed stream)")
+		fmt.Fprintln(console, "7: Speed Ladder (one combo again and again, with less time each tier)")
+		fmt.Fprintln(console, "q: Quit")
+
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Scan()
+		choice = scanner.Text()
+	}
+
+	var recorder *macroRecorder
+	if replay != nil {
+		input = NewScriptedInput(replay.Events, *demoSpeed, input)
+	} else if *record != "" {
+		recorder = newMacroRecorder(input)
+		input = recorder
+	}
+
+	if choice == "q" || choice == "Q" {
+		logf("Exiting...\n")
+		return
+	}
+	if len(players) > 0 {
+		playHotSeat(choice, players, seed)
+		waitForExit()
+		return
+	}
+	if ghost != nil {
+		ghost.begin()
+	}
+	result, ok := playMode(choice, username)
+	if !ok {
+		fmt.Fprintln(console, "Invalid option, please restart the program.")
+		os.Exit(1)
+	}
+
+	if *logFile != "" {
+		if err := writeRunLog(*logFile, runLog); err != nil {
+			fmt.Fprintln(console, "Error saving run log:", err)
+		}
+	}
+
+	if recorder != nil {
+		m := &macro{Seed: seed, Mode: choice, Events: recorder.Recorded(), Splits: clearedSplits(runLog), Derived: &runDerived}
+		if err := saveMacro(*record, m); err != nil {
+			fmt.Fprintln(console, "Error saving macro:", err)
+		}
+	}
+
+	fmt.Printf("Congratulations %s! Final Score: %s in %s seconds\n", username, fmtInt(result.Score), fmtFloat(result.Elapsed, 2))
+	if len(result.Outcomes) > 0 {
+		fmt.Println("Run:", renderFilmstrip(result.Outcomes))
+	}
+	if result.Mode == "gauntlet" && len(result.Outcomes) > 0 && result.Outcomes[0] == outcomeCleared {
+		fmt.Printf("Gauntlet cleared in %s seconds with %s mistakes\n", fmtFloat(result.Elapsed, 2), fmtInt(result.Mistakes))
+	}
+	if l := result.Ladder; l != nil {
+		if l.Level > 0 {
+			fmt.Printf("Speed Ladder on %s: reached level %s (%s seconds)\n", l.Combo, fmtInt(l.Level), fmtFloat(l.Seconds, 2))
+		} else {
+			fmt.Printf("Speed Ladder on %s: no tier beaten\n", l.Combo)
+		}
+	}
+	if len(result.Combos) > 0 {
+		printComboRecords(os.Stdout, result.Combos)
+	} else if len(runLog) > 0 {
+		printComboEfficiency(os.Stdout, runLog)
+	}
+	if result.Mode == "timed" {
+		if all, err := loadStats(); err == nil && all[username] != nil {
+			fmt.Printf("Perfect combos: %s (%s lifetime)\n", fmtInt(result.Perfect), fmtInt(all[username].Perfect))
+		} else {
+			fmt.Printf("Perfect combos: %s\n", fmtInt(result.Perfect))
+		}
+	}
+	if len(result.Outcomes) > 0 {
+		fmt.Printf("Efficiency: %.0f%%\n", result.Efficiency*100)
+	}
+	if result.Skips > 0 {
+		fmt.Printf("Skipped %s combos (-%s points)\n", fmtInt(result.Skips), fmtInt(result.SkipPoints))
+	}
+	if result.Repeated {
+		fmt.Printf("The pool was smaller than %s combos, so some were repeated.\n", fmtInt(modeCount(result.Mode)))
+	}
+	if result.NoQuit {
+		fmt.Println("No-quit rules were enforced for this run.")
+	}
+	if ghost != nil {
+		fmt.Println(ghost.verdict())
+	}
+	for _, name := range result.Achievements {
+		fmt.Printf("Achievement unlocked: %s (%s)\n", name, achievementDescription(name))
+	}
+	if *webhook != "" && !replaying {
+		if err := postResult(*webhook, result); err != nil {
+			fmt.Fprintln(console, "Webhook failed:", err)
+		}
+	}
+	// A replay has no live arrow keys left to drill with.
+	if *drill && len(runMissed) > 0 && !replaying {
+		drillArrows(runMissed)
+	}
+	waitForExit()
+}
+
+// defaultUsername is the name used when no other name is available.
+const defaultUsername = "Anonymous"
+
+// resolveUsername returns the player's name from -user, falling back to $USER or
+// $USERNAME. The player is only prompted when no name is available, or when
+// -ask-user is set, in which case pressing Enter keeps the detected name, or
+// falls back to defaultUsername when there is none.
+func resolveUsername() string {
+	return promptUsername(os.Stdin, os.Getenv)
+}
+
+// promptUsername is resolveUsername reading the answer from in and the
+// environment through getenv.
+func promptUsername(in io.Reader, getenv func(string) string) string {
+	name := strings.TrimSpace(*userName)
+	if name == "" {
+		name = getenv("USER")
+	}
+	if name == "" {
+		name = getenv("USERNAME")
+	}
+	if name != "" && !*askUser {
+		return name
+	}
+
+	if name != "" {
+		fmt.Fprintf(console, "Enter your username [%s]: ", name)
+	} else {
+		fmt.Fprint(console, "Enter your username: ")
+	}
+	userScanner := bufio.NewScanner(in)
+	userScanner.Scan()
+	if input := strings.TrimSpace(userScanner.Text()); input != "" {
+		return input
+	}
+	if name == "" {
+		return defaultUsername
+	}
+	return name
+}
+
+// playMode runs the game mode picked from the menu for user.
+// ok is false if choice is not a menu option.
+func playMode(choice, user string) (result runResult, ok bool) {
+	runDirections, runCombos, runTransitions = map[rune]dirStat{}, map[string]comboStat{}, map[string]transStat{}
+	runSkips, runSkipPoints, runMissed = 0, 0, nil
+	packNames, runArrows = nil, 0
+	runRecords, runStreak = playerRecords{}, 0
+	livesLeft, dying = *lives, false
+	runTrail.reset(*trailLen)
+	defer func() { livesLeft, dying = 0, false }()
+	queued = nil
+	defer func() { queued = nil }()
+	runStart = time.Now()
+	runDerived = derivedRun{}
+	switch choice {
+	case "1":
+		result = playJSONCombos(modeCount("json"), user)
+		result.Mode = "json"
+	case "2":
+		result = playRandomCombos(modeCount("random"), modeLength("random"), user)
+		result.Mode = "random"
+	case "3":
+		result = playTimedJSONCombos(modeCount("timed"), modeTime("timed"), user)
+		result.Mode = "timed"
+	case "4":
+		result = playEndlessCombos(user)
+		result.Mode = "endless"
+	case "5":
+		result = playPractice(modeCount("practice"), modeLength("practice"), user)
+		result.Mode = "practice"
+	case "6":
+		result = playGauntlet()
+		result.Mode = "gauntlet"
+	case "7":
+		result = playSpeedLadder()
+		result.Mode = "ladder"
+	default:
+		return runResult{}, false
+	}
+	result.User = user
+	result.NoQuit = *noQuit
+	result.Skips, result.SkipPoints = runSkips, runSkipPoints
+	result.Mistakes = runMistakes()
+	result.Efficiency = runEfficiency()
+	if replaying {
+		return result, true
+	}
+	if ps, err := recordRunStats(user, result); err != nil {
+		fmt.Fprintln(console, "Error saving stats:", err)
+	} else if result.Achievements, err = recordAchievements(user, ps, result); err != nil {
+		fmt.Fprintln(console, "Error saving achievements:", err)
+	}
+	if result.Ladder != nil {
+		if err := recordLadder(result); err != nil {
+			fmt.Fprintln(console, "Error saving speed ladder score:", err)
+		}
+	} else if len(result.Outcomes) > 0 {
+		if err := recordScore(result); err != nil {
+			fmt.Fprintln(console, "Error saving score:", err)
+		}
+	}
+	return result, true
+}
+
+func waitForExit() {
+	fmt.Fprintln(console, "Press 'Enter' to exit.")
+	bufio.NewReader(os.Stdin).ReadBytes('\n')
+}
+
+// showSplash draws the branded splash screen and waits until a key is pressed
+// or the timeout expires, whichever comes first.
+func showSplash(timeout time.Duration) {
+	if err := termbox.Init(); err != nil {
+		return
+	}
+	defer termbox.Close()
+
+	lines := append(append([]string{}, splashArt...),
+		"",
+		"S T R A T A G E M   T R A I N E R",
+		"",
+		"For Super Earth! Press any key to continue.",
+	)
+	termbox.Clear(termbox.ColorDefault, termbox.ColorDefault)
+	w, h := termbox.Size()
+	top := (h - len(lines)) / 2
+	for i, line := range lines {
+		fg := termbox.ColorYellow | termbox.AttrBold
+		if i >= len(splashArt) {
+			fg = termbox.ColorDefault
+		}
+		drawString((w-len([]rune(line)))/2, top+i, line, fg, termbox.ColorDefault)
+	}
+	termbox.Flush()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case ev := <-input.Events():
+			if ev.Type == termbox.EventKey || ev.Type == termbox.EventError {
+				return
+			}
+		case <-timer.C:
+			return
+		}
 	}
-	return combos, nil
 }
 
-// fileExists checks if a file exists and is not a directory.
-func fileExists(filename string) bool {
-	info, err := os.Stat(filename)
-	return err == nil && !info.IsDir()
-}
+// playJSONCombos processes count random combos from the JSON file (non-timed mode).
+// Returns the run's result.
+func playJSONCombos(count int, user string) (result runResult) {
+	startTime := time.Now()
+	if err := screen.Open(); err != nil {
+		fmt.Fprintln(console, "Failed to initialize the display:", err)
+		return runResult{}
+	}
+	defer screen.Close()
 
-func main() {
-	rand.Seed(time.Now().UnixNano())
+	combos, err := loadPack()
+	if err != nil {
+		fmt.Fprintf(console, "Error loading combinations: %s\n", err)
+		return runResult{}
+	}
 
-	// Ask for username.
-	fmt.Print("Enter your username: ")
-	userScanner := bufio.NewScanner(os.Stdin)
-	userScanner.Scan()
-	username := strings.TrimSpace(userScanner.Text())
+	selected, repeated := selectRun(combos, count, user)
+	orderCombos(selected)
+	if !showBriefing(selected) {
+		return runResult{}
+	}
+	startTime = time.Now()
+	defer func() { result.Repeated = repeated }()
+	count = len(selected)
 
-	// Show options.
-	fmt.Println("Choose an option:")
-	fmt.Println("1: JSON Combos (10 random combos from file)")
-	fmt.Println("2: Random Combos (10 random sequences of 6 arrows)")
-	fmt.Println("3: Timed JSON Combos (30 seconds to finish 10 random combos)")
-	fmt.Println("q: Quit")
+	var pool poolProgress
+	if count == len(combos) {
+		pool.Total = count
+	}
 
-	scanner := bufio.NewScanner(os.Stdin)
-	scanner.Scan()
-	input := scanner.Text()
+	planned := count
+	if *bossRun {
+		planned++
+	}
+	var outcomes []outcome
 
-	var score int
-	var elapsed float64
+	totalScore := 0
+	logf("JSON Combos Mode: Solve %d random combos from the file!\n", count)
+	for i := 0; i < count; i++ {
+		if !breakReminder(i, totalScore) {
+			logf("You exited early. Final Score: %d\n", totalScore)
+			return newRunResult(totalScore, startTime, outcomes, planned)
+		}
+		combo := selected[i]
+		queued = selected[i+1:]
+		seq := arrowSequenceFromCombination(combo.Sequence)
+		o, _ := processSequence(seq, &totalScore, combo.Name, pool)
+		if outOfLives() {
+			logf("Out of lives! Final Score: %d\n", totalScore)
+			return newRunResult(totalScore, startTime, append(outcomes, o), planned)
+		}
+		if o == outcomeFailed {
+			logf("You exited early. Final Score: %d\n", totalScore)
+			return newRunResult(totalScore, startTime, append(outcomes, outcomeFailed), planned)
+		}
+		outcomes = append(outcomes, o)
+		if o == outcomeCleared {
+			pool.Cleared++
+		}
+	}
 
-	switch input {
-	case "1":
-		score, elapsed = playJSONCombos(10)
-	case "2":
-		score, elapsed = playRandomCombos(10)
-	case "3":
-		score, elapsed = playTimedJSONCombos(10, 30*time.Second)
-	case "q", "Q":
-		fmt.Println("Exiting...")
-		return
-	default:
-		fmt.Println("Invalid option, please restart the program.")
-		return
+	if *bossRun {
+		name, seq := bossCombo(combos)
+		o, _ := processSequence(seq, &totalScore, bossTitle(name), pool)
+		if outOfLives() {
+			logf("Out of lives! Final Score: %d\n", totalScore)
+			return newRunResult(totalScore, startTime, append(outcomes, o), planned)
+		}
+		if o == outcomeFailed {
+			logf("You exited early. Final Score: %d\n", totalScore)
+			return newRunResult(totalScore, startTime, append(outcomes, outcomeFailed), planned)
+		}
+		outcomes = append(outcomes, o)
+		if o == outcomeCleared {
+			totalScore += bossBonus
+			logf("Boss defeated! +%d points\n", bossBonus)
+		}
 	}
+	return newRunResult(totalScore, startTime, outcomes, planned)
+}
 
-	fmt.Printf("Congratulations %s! Final Score: %d in %.2f seconds\n", username, score, elapsed)
-	waitForExit()
+// playRandomCombos processes count rounds of random sequences of length arrows.
+// Returns the run's result.
+func playRandomCombos(count, length int, user string) runResult {
+	return playRandomSequences(count, length, 0, runEase(user))
 }
 
-func waitForExit() {
-	fmt.Println("Press 'Enter' to exit.")
-	bufio.NewReader(os.Stdin).ReadBytes('\n')
+// playPractice is random mode weighted toward the direction user has been
+// least accurate on. Without enough stats yet it plays like random mode.
+func playPractice(count, length int, user string) runResult {
+	focus, ok := runFocus(user)
+	if ok {
+		logf("Targeting your weak direction: %s\n", directionNames[focus])
+	} else {
+		logf("Not enough stats to find a weak direction yet; practising all directions.\n")
+	}
+	return playRandomSequences(count, length, focus, runEase(user))
 }
 
-// playJSONCombos processes count random combos from the JSON file (non-timed mode).
-// Returns the total score and elapsed time.
-func playJSONCombos(count int) (int, float64) {
-	startTime := time.Now()
-	if err := termbox.Init(); err != nil {
-		fmt.Println("Failed to initialize termbox:", err)
-		return 0, 0
+// runEase is welcomeBackEase for user, or the replayed run's ease.
+func runEase(user string) int {
+	if replayDerived != nil {
+		return replayDerived.Ease
 	}
-	defer termbox.Close()
+	runDerived.Ease = welcomeBackEase(user)
+	return runDerived.Ease
+}
 
-	combos, err := loadCombinations("stratagems.json")
-	if err != nil {
-		fmt.Printf("Error loading combinations: %s\n", err)
-		return 0, 0
+// runFocus is weakestDirection for user, or the replayed run's focus.
+func runFocus(user string) (rune, bool) {
+	if replayDerived != nil {
+		if replayDerived.Focus == "" {
+			return 0, false
+		}
+		return rune(replayDerived.Focus[0]), true
+	}
+	focus, ok := weakestDirection(user)
+	if ok {
+		runDerived.Focus = string(focus)
 	}
+	return focus, ok
+}
 
-	rand.Shuffle(len(combos), func(i, j int) {
-		combos[i], combos[j] = combos[j], combos[i]
-	})
-	if count > len(combos) {
-		count = len(combos)
+// playRandomSequences plays count random sequences of length arrows, biased
+// toward focus unless it is zero. The first sequences are ease arrows
+// shorter, growing by one each round until they reach full length.
+func playRandomSequences(count, length int, focus rune, ease int) runResult {
+	startTime := time.Now()
+	if err := screen.Open(); err != nil {
+		fmt.Fprintln(console, "Failed to initialize the display:", err)
+		return runResult{}
+	}
+	defer screen.Close()
+
+	planned := count
+	if *bossRun {
+		planned++
 	}
+	var outcomes []outcome
 
 	totalScore := 0
-	fmt.Println("JSON Combos Mode: Solve 10 random combos from the file!")
+	logf("Random Combo Mode: Solve %d random combos (each with %d arrows)!\n", count, length)
 	for i := 0; i < count; i++ {
-		combo := combos[i]
-		seq := arrowSequenceFromCombination(combo.Sequence)
-		completed, _ := processSequence(seq, &totalScore, combo.Name)
-		if !completed {
-			fmt.Printf("You exited early. Final Score: %d\n", totalScore)
-			return totalScore, time.Since(startTime).Seconds()
+		if !breakReminder(i, totalScore) {
+			logf("You exited early. Final Score: %d\n", totalScore)
+			return newRunResult(totalScore, startTime, outcomes, planned)
+		}
+		title := "Random"
+		if focus != 0 {
+			title = "Practice: " + directionNames[focus]
+		}
+		// Never ease a sequence below one arrow.
+		seq := randomArrows(max(1, min(length, length-ease+i)), focus)
+		o, _ := processSequence(seq, &totalScore, title, poolProgress{})
+		if outOfLives() {
+			logf("Out of lives! Final Score: %d\n", totalScore)
+			return newRunResult(totalScore, startTime, append(outcomes, o), planned)
+		}
+		if o == outcomeFailed {
+			logf("You exited early. Final Score: %d\n", totalScore)
+			return newRunResult(totalScore, startTime, append(outcomes, outcomeFailed), planned)
 		}
+		outcomes = append(outcomes, o)
 	}
-	return totalScore, time.Since(startTime).Seconds()
+
+	if *bossRun {
+		name, seq := bossCombo(nil)
+		o, _ := processSequence(seq, &totalScore, bossTitle(name), poolProgress{})
+		if outOfLives() {
+			logf("Out of lives! Final Score: %d\n", totalScore)
+			return newRunResult(totalScore, startTime, append(outcomes, o), planned)
+		}
+		if o == outcomeFailed {
+			logf("You exited early. Final Score: %d\n", totalScore)
+			return newRunResult(totalScore, startTime, append(outcomes, outcomeFailed), planned)
+		}
+		outcomes = append(outcomes, o)
+		if o == outcomeCleared {
+			totalScore += bossBonus
+			logf("Boss defeated! +%d points\n", bossBonus)
+		}
+	}
+	return newRunResult(totalScore, startTime, outcomes, planned)
 }
 
-// playRandomCombos processes count rounds of random sequences (each with 6 arrows).
-// Returns the total score and elapsed time.
-func playRandomCombos(count int) (int, float64) {
-	startTime := time.Now()
-	if err := termbox.Init(); err != nil {
-		fmt.Println("Failed to initialize termbox:", err)
-		return 0, 0
+// playGauntlet plays the whole pool as one continuous stream of arrows,
+// timed as a single combo.
+func playGauntlet() runResult {
+	if err := screen.Open(); err != nil {
+		fmt.Fprintln(console, "Failed to initialize the display:", err)
+		return runResult{}
+	}
+	defer screen.Close()
+
+	combos, err := loadPack()
+	if err != nil {
+		fmt.Fprintf(console, "Error loading combinations: %s\n", err)
+		return runResult{}
+	}
+	selected, _ := selectCombos(combos, len(combos))
+	orderCombos(selected)
+	var seq []Arrow
+	for _, combo := range selected {
+		seq = append(seq, arrowSequenceFromCombination(combo.Sequence)...)
 	}
-	defer termbox.Close()
 
 	totalScore := 0
-	fmt.Println("Random Combo Mode: Solve 10 random combos (each with 6 arrows)!")
-	for i := 0; i < count; i++ {
-		seq := randomArrows(6)
-		completed, _ := processSequence(seq, &totalScore, "Random")
-		if !completed {
-			fmt.Printf("You exited early. Final Score: %d\n", totalScore)
-			return totalScore, time.Since(startTime).Seconds()
+	logf("Gauntlet: %d combos, %d arrows, no breaks!\n", len(selected), len(seq))
+	startTime := time.Now()
+	o, _ := processSequence(seq, &totalScore, fmt.Sprintf("Gauntlet (%d combos)", len(selected)), poolProgress{})
+	return newRunResult(totalScore, startTime, []outcome{o}, 1)
+}
+
+// drillArrows prompts for each of arrows on its own, the missed arrows of
+// the run just played. Drill scores don't count toward anything.
+func drillArrows(arrows []Arrow) {
+	if err := screen.Open(); err != nil {
+		fmt.Fprintln(console, "Failed to initialize the display:", err)
+		return
+	}
+
+	score := 0
+	cleared := 0
+	for i, arrow := range arrows {
+		title := fmt.Sprintf("Drill: %s (%d/%d)", directionNames[arrow.Dir], i+1, len(arrows))
+		o, _ := processSequence([]Arrow{arrow}, &score, title, poolProgress{})
+		if o == outcomeFailed {
+			break
+		}
+		if o == outcomeCleared {
+			cleared++
 		}
 	}
-	return totalScore, time.Since(startTime).Seconds()
+	screen.Close()
+	logf("Drilled %d of %d missed arrows.\n", cleared, len(arrows))
 }
 
 // playTimedJSONCombos processes count random JSON combos under an overall time limit.
 // The user has the given duration (e.g. 30 seconds) to complete as many combos as possible.
 // Each combo earns bonus points if completed quickly.
-// Returns total score and elapsed time.
-func playTimedJSONCombos(count int, timeLimit time.Duration) (int, float64) {
+// Returns the run's result.
+func playTimedJSONCombos(count int, timeLimit time.Duration, user string) (result runResult) {
 	overallDeadline := time.Now().Add(timeLimit)
 	startTime := time.Now()
-	if err := termbox.Init(); err != nil {
-		fmt.Println("Failed to initialize termbox:", err)
-		return 0, 0
+	if err := screen.Open(); err != nil {
+		fmt.Fprintln(console, "Failed to initialize the display:", err)
+		return runResult{}
 	}
-	defer termbox.Close()
+	defer screen.Close()
 
-	combos, err := loadCombinations("stratagems.json")
+	combos, err := loadPack()
 	if err != nil {
-		fmt.Printf("Error loading combinations: %s\n", err)
-		return 0, 0
+		fmt.Fprintf(console, "Error loading combinations: %s\n", err)
+		return runResult{}
 	}
-	rand.Shuffle(len(combos), func(i, j int) {
-		combos[i], combos[j] = combos[j], combos[i]
-	})
-	if count > len(combos) {
-		count = len(combos)
+	selected, repeated := selectRun(combos, count, user)
+	orderCombos(selected)
+	if !showBriefing(selected) {
+		return runResult{}
+	}
+	// The clock starts once the briefing is dismissed.
+	overallDeadline, startTime = time.Now().Add(timeLimit), time.Now()
+	clockCredit = 0
+	var records []comboRecord
+	perfectBanner = ""
+	defer func() {
+		result.Repeated, result.Combos = repeated, records
+		for _, rec := range records {
+			if rec.Bonus == perfectBonus {
+				result.Perfect++
+			}
+		}
+		showClosingBanner(result.Score)
+	}()
+	count = len(selected)
+
+	var pool poolProgress
+	if count == len(combos) {
+		pool.Total = count
+	}
+
+	planned := count
+	if *bossRun {
+		planned++
 	}
+	var outcomes []outcome
 
 	totalScore := 0
-	fmt.Println("Timed JSON Combos Mode: You have 30 seconds to solve 10 random combos!")
+	logf("Timed JSON Combos Mode: You have %.0f seconds to solve %d random combos!\n", timeLimit.Seconds(), count)
 	for i := 0; i < count; i++ {
-		if time.Now().After(overallDeadline) {
-			fmt.Println("Time's up!")
+		if time.Now().After(overallDeadline.Add(clockCredit)) {
+			logf("Time's up!\n")
 			break
 		}
-		combo := combos[i]
+		combo := selected[i]
+		queued = selected[i+1:]
 		seq := arrowSequenceFromCombination(combo.Sequence)
 		// Use the timed version of processSequence.
-		completed, _, _ := processSequenceTimed(seq, &totalScore, combo.Name, overallDeadline)
+		completed, rec := processSequenceTimed(seq, &totalScore, combo.Name, overallDeadline.Add(clockCredit), pool)
+		o := outcomeFailed
+		if completed {
+			records = append(records, rec)
+			o = outcomeCleared
+		}
+		outcomes = append(outcomes, o)
+		// -finish-on-death completes the fatal combo, so lives are checked
+		// whatever its outcome.
+		if outOfLives() {
+			logf("Out of lives! Final Score: %d\n", totalScore)
+			return newRunResult(totalScore, startTime, outcomes, planned)
+		}
+		if !completed {
+			logf("You exited early. Final Score: %d\n", totalScore)
+			return newRunResult(totalScore, startTime, outcomes, planned)
+		}
+		pool.Cleared++
+	}
+
+	if *bossRun && time.Now().Before(overallDeadline.Add(clockCredit)) {
+		name, seq := bossCombo(combos)
+		completed, rec := processSequenceTimed(seq, &totalScore, bossTitle(name), overallDeadline.Add(clockCredit), pool)
 		if !completed {
-			fmt.Printf("You exited early. Final Score: %d\n", totalScore)
-			return totalScore, time.Since(startTime).Seconds()
+			if outOfLives() {
+				logf("Out of lives! Final Score: %d\n", totalScore)
+			} else {
+				logf("You exited early. Final Score: %d\n", totalScore)
+			}
+			return newRunResult(totalScore, startTime, append(outcomes, outcomeFailed), planned)
+		}
+		records = append(records, rec)
+		totalScore += bossBonus
+		outcomes = append(outcomes, outcomeCleared)
+		logf("Boss defeated! +%d points\n", bossBonus)
+	}
+	return newRunResult(totalScore, startTime, outcomes, planned)
+}
+
+// selectRun picks the combos for user's run: the revenge set with -revenge,
+// or a normal selection otherwise or when there is no failure history.
+func selectRun(combos []combination, count int, user string) ([]combination, bool) {
+	if *revenge {
+		if selected, ok := revengeSet(combos, count, user); ok {
+			return selected, false
+		}
+		logf("No failure history yet; picking combos at random.\n")
+	}
+	return selectCombos(combos, count)
+}
+
+// selectCombos shuffles combos and returns the first count of them to play.
+// When count exceeds the pool, every combo is played once, unless -repeat is
+// set: then the pool is reshuffled after each full pass until count combos
+// are selected, and repeated is true.
+func selectCombos(combos []combination, count int) (selected []combination, repeated bool) {
+	shuffle := func() {
+		rng.Shuffle(len(combos), func(i, j int) {
+			combos[i], combos[j] = combos[j], combos[i]
+		})
+	}
+	shuffle()
+	if count <= len(combos) {
+		return combos[:count], false
+	}
+	if !*repeatPool || len(combos) == 0 {
+		return combos, false
+	}
+	for len(selected) < count {
+		pass := combos
+		if n := count - len(selected); n < len(pass) {
+			pass = pass[:n]
+		}
+		selected = append(selected, pass...)
+		shuffle()
+	}
+	return selected, true
+}
+
+// orderCombos applies -order to the combos selected for a run. With ramp
+// they are sorted easiest to hardest by estimateDifficulty, and the range
+// covered is announced.
+func orderCombos(selected []combination) {
+	if *order != "ramp" || len(selected) == 0 {
+		return
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		return estimateDifficulty(selected[i].Sequence) < estimateDifficulty(selected[j].Sequence)
+	})
+	logf("Difficulty ramp: %d to %d\n", estimateDifficulty(selected[0].Sequence), estimateDifficulty(selected[len(selected)-1].Sequence))
+}
+
+// bossCombo picks the final boss for -boss: the hardest combo in pool, or a
+// long random sequence when the run has no pool to draw from.
+func bossCombo(pool []combination) (string, []Arrow) {
+	if len(pool) == 0 {
+		return "Random", randomArrows(bossLength, 0)
+	}
+	hardest := pool[0]
+	for _, combo := range pool[1:] {
+		if estimateDifficulty(combo.Sequence) > estimateDifficulty(hardest.Sequence) {
+			hardest = combo
+		}
+	}
+	return hardest.Name, arrowSequenceFromCombination(hardest.Sequence)
+}
+
+// bossTitle frames a boss combo's name for the header.
+func bossTitle(name string) string {
+	return fmt.Sprintf("☠ FINAL BOSS ☠ %s (+%d if cleared)", name, bossBonus)
+}
+
+// estimateDifficulty scores how hard a sequence like "UDLR" is to enter.
+// Every arrow counts, and every change of direction counts again, since
+// repeated presses of the same key are much quicker than switching keys.
+func estimateDifficulty(sequence string) int {
+	difficulty := 0
+	var prev rune
+	for _, char := range sequence {
+		if _, ok := arrowsMap[char]; !ok {
+			continue
+		}
+		difficulty++
+		if prev != 0 && char != prev {
+			difficulty++
+		}
+		prev = char
+	}
+	return difficulty
+}
+
+// renderFilmstrip draws a run's outcomes in order as a compact strip of icons:
+// ✓ for a cleared combo, ✗ for a failed one and ⏭ for one never attempted.
+func renderFilmstrip(outcomes []outcome) string {
+	var b strings.Builder
+	for i, o := range outcomes {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		switch o {
+		case outcomeCleared:
+			b.WriteString("✓")
+		case outcomeFailed:
+			b.WriteString("✗")
+		default:
+			b.WriteString("⏭")
+		}
+	}
+	return b.String()
+}
+
+// printComboRecords prints timed mode's per-combo breakdown as a table,
+// fastest combo first.
+func printComboRecords(w io.Writer, records []comboRecord) {
+	sorted := append([]comboRecord(nil), records...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Seconds < sorted[j].Seconds })
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Combo\tTime\tBonus\tMistakes\tEfficiency")
+	for _, r := range sorted {
+		fmt.Fprintf(tw, "%s\t%ss\t+%s\t%s\t%.0f%%\n", r.Name, fmtFloat(r.Seconds, 2), fmtInt(r.Bonus), fmtInt(r.Mistakes), efficiency(r.Arrows, r.Mistakes)*100)
+	}
+	tw.Flush()
+}
+
+// playEndlessCombos serves combos from the JSON file until the player quits,
+// reshuffling the pool after every full pass. The run is checkpointed to disk
+// every -autosave-combos combos or -autosave-interval, so a crash during a
+// marathon session does not lose its stats.
+// Returns the run's result.
+func playEndlessCombos(user string) runResult {
+	startTime := time.Now()
+	if err := screen.Open(); err != nil {
+		fmt.Fprintln(console, "Failed to initialize the display:", err)
+		return runResult{}
+	}
+	defer screen.Close()
+
+	combos, err := loadPack()
+	if err != nil {
+		fmt.Fprintf(console, "Error loading combinations: %s\n", err)
+		return runResult{}
+	}
+
+	cp := checkpoint{User: user, Started: startTime}
+	lastSave := time.Now()
+
+	var outcomes []outcome
+	totalScore := 0
+	// The checkpoint is only removed when the run ends cleanly; after a
+	// crash or panic it stays behind for offerCheckpoint.
+	finish := func() runResult {
+		os.Remove(checkpointFile)
+		return newRunResult(totalScore, startTime, outcomes, len(outcomes))
+	}
+	logf("Endless Mode: Solve combos until you quit!\n")
+	for {
+		rng.Shuffle(len(combos), func(i, j int) {
+			combos[i], combos[j] = combos[j], combos[i]
+		})
+		for j, combo := range combos {
+			queued = combos[j+1:]
+			if !breakReminder(len(outcomes), totalScore) || len(outcomes) > 0 && !countdown(*rest, totalScore) {
+				logf("You exited. Final Score: %d\n", totalScore)
+				return finish()
+			}
+			seq := arrowSequenceFromCombination(combo.Sequence)
+			o, _ := processSequence(seq, &totalScore, combo.Name, poolProgress{})
+			outcomes = append(outcomes, o)
+			if outOfLives() {
+				logf("Out of lives! Final Score: %d\n", totalScore)
+				return finish()
+			}
+			if o == outcomeFailed {
+				logf("You exited. Final Score: %d\n", totalScore)
+				return finish()
+			}
+
+			// Skipped combos count toward the autosave, but not as completed.
+			cp.Score = totalScore
+			if o == outcomeCleared {
+				cp.Completed++
+			}
+			dueByCount := *saveEvery > 0 && len(outcomes)%*saveEvery == 0
+			dueByTime := *saveInterval > 0 && time.Since(lastSave) >= *saveInterval
+			if (dueByCount || dueByTime) && !replaying {
+				if err := saveCheckpoint(cp); err != nil {
+					logf("Could not save checkpoint: %s\n", err)
+				}
+				lastSave = time.Now()
+			}
 		}
 	}
-	return totalScore, time.Since(startTime).Seconds()
 }
 
-// randomArrows generates a random sequence of n arrows.
-func randomArrows(n int) []Arrow {
+// rng makes every random choice of a run. main and playHotSeat reseed it
+// from the run's seed, so a replay, a ghost race or each hot-seat player is
+// dealt the same combos again; the global source can't promise that.
+var rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+// focusShare is the chance that each arrow of a focused random sequence is
+// the focus direction.
+const focusShare = 0.6
+
+// randomArrows generates a random sequence of n arrows. A non-zero focus
+// direction makes up about focusShare of the arrows instead of a quarter.
+func randomArrows(n int, focus rune) []Arrow {
 	keys := []rune{'U', 'D', 'L', 'R'}
 	result := make([]Arrow, n)
 	for i := range result {
-		rk := keys[rand.Intn(len(keys))]
+		rk := keys[rng.Intn(len(keys))]
+		if focus != 0 && rng.Float64() < focusShare {
+			rk = focus
+		}
 		result[i] = arrowsMap[rk]
 	}
 	return result
 }
 
+// settleCombo discards key presses for the -settle window after a combo is
+// first drawn, so a key already in flight doesn't count against it. Quit keys
+// still work; it returns false if one was pressed.
+func settleCombo() bool {
+	if *settle <= 0 {
+		return true
+	}
+	done := time.After(*settle)
+	for {
+		select {
+		case ev := <-input.Events():
+			if ev.Type == termbox.EventKey && isQuitKey(ev) {
+				return false
+			} else if ev.Type == termbox.EventError {
+				panic(ev.Err)
+			}
+		case <-done:
+			return true
+		}
+	}
+}
+
+// briefingPage is how many combos the -briefing screen lists at a time.
+const briefingPage = 20
+
+// showBriefing lists combos before a run for -briefing, scrolled with the
+// arrow keys or Page Up/Down and dismissed with Enter. It returns false if
+// the player quit instead.
+func showBriefing(combos []combination) bool {
+	if *briefing == "" {
+		return true
+	}
+	nameWidth := 0
+	for _, combo := range combos {
+		nameWidth = max(nameWidth, len([]rune(combo.Name)))
+	}
+	lines := make([]string, len(combos))
+	for i, combo := range combos {
+		lines[i] = fmt.Sprintf("%2d. %s", i+1, combo.Name)
+		if *briefing == "full" {
+			lines[i] += strings.Repeat(" ", nameWidth-len([]rune(combo.Name)))
+			var glyphs []string
+			for _, dir := range combo.Sequence {
+				glyphs = append(glyphs, arrowGlyphs[dir])
+			}
+			lines[i] += "  " + strings.Join(glyphs, " ")
+		}
+	}
+	top := 0
+	last := max(0, len(lines)-briefingPage)
+	f := frame{Title: fmt.Sprintf("Mission briefing: %d combos", len(combos)), Hint: -1, Fresh: true}
+	for {
+		f.Status = lines[top:min(top+briefingPage, len(lines))]
+		f.Feedback = "Enter to start"
+		if last > 0 {
+			f.Feedback = fmt.Sprintf("Showing %d-%d of %d. Up/Down or PgUp/PgDn to scroll, Enter to start", top+1, top+len(f.Status), len(lines))
+		}
+		screen.Render(f)
+		f.Fresh = false
+		ev := <-input.Events()
+		if ev.Type == termbox.EventError {
+			panic(ev.Err)
+		}
+		if ev.Type != termbox.EventKey {
+			continue
+		}
+		switch {
+		case ev.Key == termbox.KeyEnter:
+			return true
+		case isQuitKey(ev):
+			return false
+		case ev.Key == termbox.KeyArrowUp:
+			top = max(0, top-1)
+		case ev.Key == termbox.KeyArrowDown:
+			top = min(last, top+1)
+		case ev.Key == termbox.KeyPgup:
+			top = max(0, top-briefingPage)
+		case ev.Key == termbox.KeyPgdn:
+			top = min(last, top+briefingPage)
+		}
+	}
+}
+
+// breakReminder pauses for a "Take a break?" prompt once every -break-every
+// combos, given how many have been played so far. It returns false if the
+// player quits at the prompt rather than playing on.
+func breakReminder(played, score int) bool {
+	if *breakEvery <= 0 || played == 0 || played%*breakEvery != 0 {
+		return true
+	}
+	screen.Render(frame{
+		Title:    "Take a break?",
+		Score:    score,
+		Status:   []string{fmt.Sprintf("That's %d combos in a row. Stretch, rest your eyes, have some water.", played)},
+		Hint:     -1,
+		Feedback: "Enter to keep playing, Esc to stop",
+		Fresh:    true,
+	})
+	return awaitEnter()
+}
+
+// awaitEnter waits for Enter, returning true, or a quit key, returning false.
+func awaitEnter() bool {
+	for {
+		ev := <-input.Events()
+		if ev.Type == termbox.EventError {
+			panic(ev.Err)
+		}
+		if ev.Type != termbox.EventKey {
+			continue
+		}
+		if ev.Key == termbox.KeyEnter {
+			return true
+		}
+		if isQuitKey(ev) {
+			return false
+		}
+	}
+}
+
+// countdown shows a "next combo" countdown for d, ignoring key presses other
+// than quit keys. It returns false if the player quit.
+func countdown(d time.Duration, score int) bool {
+	if d <= 0 {
+		return true
+	}
+	end := time.Now().Add(d)
+	f := frame{Title: "Get ready", Score: score, Hint: -1, Fresh: true}
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		left := time.Until(end)
+		if left <= 0 {
+			return true
+		}
+		f.Status = []string{fmt.Sprintf("Next combo in %.1fs", left.Seconds())}
+		screen.Render(f)
+		f.Fresh = false
+		select {
+		case ev := <-input.Events():
+			if ev.Type == termbox.EventKey && isQuitKey(ev) {
+				return false
+			} else if ev.Type == termbox.EventError {
+				panic(ev.Err)
+			}
+		case <-ticker.C:
+		}
+	}
+}
+
+// keySeen records that at least one key event has arrived during play.
+var keySeen bool
+
+// noInputMessage is the diagnostic shown by -input-check.
+const noInputMessage = "No key events detected — is your terminal forwarding keys?"
+
+// strictMessage is the feedback for a -strict reset to input position back.
+func strictMessage(back int) string {
+	if back == 0 {
+		return "Wrong key, back to the start!"
+	}
+	return "Wrong key, back to the last checkpoint!"
+}
+
+// noInputTimer fires after -input-check while no key has been seen yet, so a
+// session whose keys never arrive says so instead of blocking silently. Once
+// a key has arrived it returns nil, which never fires.
+func noInputTimer() <-chan time.Time {
+	if keySeen || *inputCheck <= 0 {
+		return nil
+	}
+	return time.After(*inputCheck)
+}
+
+// runMissed lists the arrows the player pressed a wrong key on during the
+// current run, once per arrow of a combo, for -drill.
+var runMissed []Arrow
+
+// queued holds the combos still to come after the current one in modes
+// that know them, for -lookahead. Each mode's loop updates it.
+var queued []combination
+
+// upcoming returns the queued combos to show with -lookahead.
+func upcoming() []combination {
+	if *lookahead <= 0 {
+		return nil
+	}
+	return queued[:min(*lookahead, len(queued))]
+}
+
+// runSkips counts the combos skipped in the current run, and runSkipPoints
+// the points those skips actually cost; playMode resets both.
+var runSkips, runSkipPoints int
+
 // processSequence is the non-timed version.
 // It processes a sequence of arrows, updating the total score.
-// Returns (completed, scoreEarned).
-func processSequence(sequence []Arrow, totalScore *int, title string) (bool, int) {
+// Returns the combo's outcome, failed if the player quit, and the score earned.
+func processSequence(sequence []Arrow, totalScore *int, title string, pool poolProgress) (outcome, int) {
 	score := 0
-	printArrows(sequence, *totalScore, title)
-	termbox.Flush()
-	for _, arrow := range sequence {
+	mistakes := 0
+	reached := 0 // arrows entered correctly for the first time, for efficiency
+	f := frame{Title: title, Score: *totalScore, Pool: pool, Sequence: sequence, Hint: -1, Upcoming: upcoming()}
+	f.Trail = runTrail.String()
+	if !*noQuit {
+		f.Status = []string{fmt.Sprintf("Tab skips this combo (-%d points)", *skipPenalty)}
+	}
+	f.Fresh = true
+	screen.Render(f)
+	f.Fresh = false
+	if !settleCombo() {
+		logf("Exiting...\n")
+		logCombo(title, outcomeFailed, 0, score, 0, reached)
+		return outcomeFailed, score
+	}
+	comboStart := time.Now()
+	noInput := noInputTimer()
+	missedAt := -1
+	order := inputOrder(sequence)
+	lastHit := comboStart
+	for i := 0; i < len(order); i++ {
+		arrow := order[i]
+		// The hint fires once per arrow if no correct key arrives in time,
+		// but never in -strict or -no-quit runs, which allow no help.
+		var hint <-chan time.Time
+		if *hintWait > 0 && !*strict && !*noQuit {
+			hint = time.After(*hintWait)
+		}
+		var unshake <-chan time.Time
+	waitKey:
 		for {
-			ev := termbox.PollEvent()
-			if ev.Type == termbox.EventKey {
-				if ev.Key == arrow.Key {
-					fmt.Println("Correct!")
-					score += 20
-					break // Move to next arrow.
-				} else if ev.Key == termbox.KeyEsc || ev.Ch == 'q' || ev.Key == termbox.KeyCtrlC {
-					fmt.Println("Exiting...")
-					return false, score
-				} else {
-					fmt.Println("Wrong key, try again!")
-					score -= 5
+			select {
+			case ev := <-input.Events():
+				if ev.Type == termbox.EventKey {
+					keySeen = true
+					if arrow.matches(ev) {
+						tallyArrow(arrow, true)
+						runTrail.push(true)
+						f.Trail = runTrail.String()
+						tallyTransition(order, i, true, time.Since(lastHit))
+						lastHit = time.Now()
+						f.Feedback = feedback("Correct!")
+						if *strict && checkpointBefore(order, i+1) {
+							f.Feedback = feedback("Checkpoint!")
+						}
+						score += arrowPoints(arrow)
+						reached = max(reached, i+1)
+						f.Entered, f.Hint, f.Score = i+1, -1, *totalScore+score
+						f.Tick = progressTick(i + 1)
+						screen.Render(f)
+						f.Tick = 0
+						break waitKey // Move to next arrow.
+					} else if isQuitKey(ev) {
+						logf("Exiting...\n")
+						logCombo(title, outcomeFailed, time.Since(comboStart), score, mistakes, reached)
+						return outcomeFailed, score
+					} else if isSkipKey(ev) {
+						before := score
+						score = penalize(score, *skipPenalty)
+						*totalScore += score
+						runSkips++
+						runSkipPoints += before - score
+						logCombo(title, outcomeSkipped, time.Since(comboStart), score, mistakes, reached)
+						return outcomeSkipped, score
+					} else if isBlockedQuit(ev) {
+						f.Feedback = blockedQuitMessage
+						screen.Render(f)
+					} else if msg, ok := handleToggle(ev); ok {
+						f.Feedback = msg
+						screen.Render(f)
+					} else {
+						tallyArrow(arrow, false)
+						runTrail.push(false)
+						f.Trail = runTrail.String()
+						tallyTransition(order, i, false, 0)
+						if missedAt != i {
+							runMissed, missedAt = append(runMissed, arrow), i
+						}
+						f.Feedback = feedback("Wrong key, try again!")
+						score = penalize(score, 5)
+						mistakes++
+						f.Score = *totalScore + score
+						if *shake {
+							f.Shake = true
+							unshake = time.After(shakeDuration)
+						}
+						f.Bell = wrongKeyBell()
+						if loseLife() {
+							f.Feedback = "Out of lives!"
+							screen.Render(f)
+							logCombo(title, outcomeFailed, time.Since(comboStart), score, mistakes, reached)
+							return outcomeFailed, score
+						}
+						if back, points := strictReset(order, i); *strict && back < i {
+							score = penalize(score, points)
+							f.Entered, f.Hint, f.Score = back, -1, *totalScore+score
+							f.Feedback = feedback(strictMessage(back))
+							screen.Render(f)
+							f.Bell = false
+							i = back - 1 // the loop moves on to back
+							break waitKey
+						}
+						screen.Render(f)
+						f.Bell = false
+					}
+				} else if ev.Type == termbox.EventError {
+					panic(ev.Err)
 				}
-			} else if ev.Type == termbox.EventError {
-				panic(ev.Err)
+			case <-hint:
+				score = penalize(score, hintPenalty)
+				f.Hint, f.Score = i, *totalScore+score
+				f.Feedback = fmt.Sprintf("Hint: press %s (-%d points)", keyName(arrow), hintPenalty)
+				screen.Render(f)
+			case <-unshake:
+				f.Shake = false
+				screen.Render(f)
+			case <-noInput:
+				f.Feedback = noInputMessage
+				screen.Render(f)
+			case <-ghostTick():
+				screen.Render(f)
 			}
 		}
 	}
+	if dying {
+		score, dying = deathCredit(score), false
+	}
 	*totalScore += score
-	return true, score
+	logCombo(title, outcomeCleared, time.Since(comboStart), score, mistakes, reached)
+	return outcomeCleared, score
+}
+
+// perfectBonus is timed mode's top speed bonus; earning it is a perfect combo.
+const perfectBonus = 100
+
+// perfectBanner is shown when the next timed combo appears, after a perfect one.
+var perfectBanner string
+
+// perfectHold is how long showClosingBanner keeps the banner up.
+const perfectHold = 800 * time.Millisecond
+
+// showClosingBanner shows a banner left over from the run's last combo on a
+// closing frame, since no next combo will show it.
+func showClosingBanner(score int) {
+	if perfectBanner == "" {
+		return
+	}
+	screen.Render(frame{Title: "Run complete", Score: score, Hint: -1, Feedback: perfectBanner, Fresh: true})
+	perfectBanner = ""
+	time.Sleep(perfectHold)
 }
 
+// clockCredit is the drawing time -fair-clock has given back during the
+// current timed run, by which its overall deadline is extended.
+var clockCredit time.Duration
+
 // processSequenceTimed is the timed version used in Option 3.
 // It uses a ticker to update the display (showing overall time remaining and combo elapsed time)
 // and a channel to receive key events.
-// Returns (completed, scoreEarned, comboDuration).
-func processSequenceTimed(sequence []Arrow, totalScore *int, title string, overallDeadline time.Time) (bool, int, time.Duration) {
+// Returns whether the combo was completed and, if so, its record.
+func processSequenceTimed(sequence []Arrow, totalScore *int, title string, overallDeadline time.Time, pool poolProgress) (bool, comboRecord) {
 	score := 0
-	comboStart := time.Now()
+	mistakes := 0
+	reached := 0 // arrows entered correctly for the first time, for efficiency
 	currentIndex := 0
-
-	events := make(chan termbox.Event)
-	go func() {
-		for {
-			events <- termbox.PollEvent()
+	expected := inputOrder(sequence)
+	f := frame{Title: title, Score: *totalScore, Pool: pool, Sequence: sequence, Hint: -1, Upcoming: upcoming()}
+	f.Trail = runTrail.String()
+	// The banner for a perfect previous combo stays up on this one's first
+	// frame, since the previous combo's screen is replaced at once.
+	f.Feedback, perfectBanner = perfectBanner, ""
+	// With -fair-clock the time each frame takes to draw is given back on
+	// both clocks, and as clockCredit to the caller's deadline.
+	var comboStart time.Time
+	render := func() {
+		start := time.Now()
+		screen.Render(f)
+		if *fairClock {
+			d := time.Since(start)
+			overallDeadline, comboStart = overallDeadline.Add(d), comboStart.Add(d)
+			clockCredit += d
 		}
-	}()
+	}
+	f.Status = timedStatus(overallDeadline, time.Now())
+	f.Fresh = true
+	render()
+	f.Fresh = false
+	// The overall clock keeps running while settling; only the speed bonus
+	// clock waits for it.
+	if !settleCombo() {
+		logf("Exiting...\n")
+		logCombo(title, outcomeFailed, 0, score, 0, reached)
+		return false, comboRecord{}
+	}
+	comboStart = time.Now()
 
 	ticker := time.NewTicker(100 * time.Millisecond)
 	defer ticker.Stop()
+	noInput := noInputTimer()
+	missedAt := -1
+	lastHit := comboStart
 
 	for currentIndex < len(sequence) {
 		remainingOverall := overallDeadline.Sub(time.Now())
 		if remainingOverall <= 0 {
-			return false, score, time.Since(comboStart)
+			logCombo(title, outcomeFailed, time.Since(comboStart), score, mistakes, reached)
+			return false, comboRecord{}
 		}
 		select {
-		case ev := <-events:
+		case ev := <-input.Events():
 			if ev.Type == termbox.EventKey {
-				if ev.Key == sequence[currentIndex].Key {
-					fmt.Println("Correct!")
-					score += 20
+				keySeen = true
+				if expected[currentIndex].matches(ev) {
+					tallyArrow(expected[currentIndex], true)
+					runTrail.push(true)
+					f.Trail = runTrail.String()
+					tallyTransition(expected, currentIndex, true, time.Since(lastHit))
+					lastHit = time.Now()
+					f.Feedback = feedback("Correct!")
+					score += arrowPoints(expected[currentIndex])
 					currentIndex++
-				} else if ev.Key == termbox.KeyEsc || ev.Ch == 'q' || ev.Key == termbox.KeyCtrlC {
-					fmt.Println("Exiting...")
-					return false, score, time.Since(comboStart)
+					reached = max(reached, currentIndex)
+					if *strict && checkpointBefore(expected, currentIndex) {
+						f.Feedback = feedback("Checkpoint!")
+					}
+					f.Tick = progressTick(currentIndex)
+				} else if isQuitKey(ev) {
+					logf("Exiting...\n")
+					logCombo(title, outcomeFailed, time.Since(comboStart), score, mistakes, reached)
+					return false, comboRecord{}
+				} else if isBlockedQuit(ev) {
+					f.Feedback = blockedQuitMessage
+				} else if msg, ok := handleToggle(ev); ok {
+					f.Feedback = msg
 				} else {
-					fmt.Println("Wrong key, try again!")
-					score -= 5
+					tallyArrow(expected[currentIndex], false)
+					runTrail.push(false)
+					f.Trail = runTrail.String()
+					tallyTransition(expected, currentIndex, false, 0)
+					if missedAt != currentIndex {
+						runMissed, missedAt = append(runMissed, expected[currentIndex]), currentIndex
+					}
+					f.Feedback = feedback("Wrong key, try again!")
+					score = penalize(score, 5)
+					mistakes++
+					f.Shake = *shake
+					f.Bell = wrongKeyBell()
+					if loseLife() {
+						f.Feedback = "Out of lives!"
+						f.Entered, f.Score = currentIndex, *totalScore+score
+						render()
+						logCombo(title, outcomeFailed, time.Since(comboStart), score, mistakes, reached)
+						return false, comboRecord{}
+					}
+					if back, points := strictReset(expected, currentIndex); *strict && back < currentIndex {
+						score = penalize(score, points)
+						currentIndex = back
+						f.Feedback = feedback(strictMessage(back))
+					}
 				}
+				f.Entered, f.Score = currentIndex, *totalScore+score
+				f.Status = timedStatus(overallDeadline, comboStart)
+				render()
+				f.Bell, f.Tick = false, 0
 			} else if ev.Type == termbox.EventError {
 				panic(ev.Err)
 			}
+		case <-noInput:
+			f.Feedback = noInputMessage
+			render()
 		case <-ticker.C:
-			printArrowsTimed(sequence, *totalScore, title, overallDeadline, comboStart, currentIndex)
-			termbox.Flush()
+			// A shake lasts until the next tick.
+			f.Shake = false
+			f.Status = timedStatus(overallDeadline, comboStart)
+			render()
 		}
 	}
 
@@ -323,67 +2165,28 @@ func processSequenceTimed(sequence []Arrow, totalScore *int, title string, overa
 	bonus := 0
 	switch {
 	case comboDuration.Seconds() <= 1:
-		bonus = 100
+		bonus = perfectBonus
+		perfectBanner = "PERFECT!"
 	case comboDuration.Seconds() <= 2:
 		bonus = 50
 	case comboDuration.Seconds() <= 3:
 		bonus = 25
 	}
 	score += bonus
-	*totalScore += score
-	return true, score, comboDuration
-}
-
-// printArrows displays the arrow art (non-timed version) along with title and current score.
-func printArrows(sequence []Arrow, currentScore int, title string) {
-	clearConsole()
-	fmt.Println("Action:", title)
-	fmt.Printf("Current Score: %d\n", currentScore)
-	lines := make([]string, 5)
-	for _, arrow := range sequence {
-		parts := strings.Split(arrow.Art, "\n")
-		for i := 0; i < 5; i++ {
-			lines[i] += parts[i] + "   "
-		}
-	}
-	for _, line := range lines {
-		fmt.Println(line)
+	if dying {
+		score, dying = deathCredit(score), false
 	}
-	fmt.Println()
-	fmt.Println()
+	*totalScore += score
+	logCombo(title, outcomeCleared, comboDuration, score, mistakes, reached)
+	return true, comboRecord{Name: title, Seconds: comboDuration.Seconds(), Bonus: bonus, Mistakes: mistakes, Arrows: len(sequence)}
 }
 
-// printArrowsTimed displays the arrow art along with title, current score, overall time remaining,
-// and elapsed time for the current combo. The current arrow is highlighted.
-func printArrowsTimed(sequence []Arrow, currentScore int, title string, overallDeadline time.Time, comboStart time.Time, currentIndex int) {
-	clearConsole()
-	remainingOverall := overallDeadline.Sub(time.Now())
-	comboElapsed := time.Since(comboStart)
-	fmt.Println("Action:", title)
-	fmt.Printf("Current Score: %d\n", currentScore)
-	fmt.Printf("Overall Time Remaining: %.1f seconds\n", remainingOverall.Seconds())
-	fmt.Printf("Combo Time Elapsed: %.2f seconds\n", comboElapsed.Seconds())
-
-	lines := make([]string, 5)
-	for i, arrow := range sequence {
-		parts := strings.Split(arrow.Art, "\n")
-		for j := 0; j < 5; j++ {
-			if i == currentIndex {
-				lines[j] += ">>" + parts[j] + "<<   "
-			} else {
-				lines[j] += parts[j] + "   "
-			}
-		}
-	}
-	for _, line := range lines {
-		fmt.Println(line)
+// logf writes an informational message to the console unless -quiet is set.
+func logf(format string, args ...any) {
+	if *quiet {
+		return
 	}
-	fmt.Println()
-}
-
-// clearConsole uses ANSI escape sequences to clear the screen.
-func clearConsole() {
-	fmt.Print("\033[H\033[2J")
+	fmt.Fprintf(console, format, args...)
 }
 
 // arrowSequenceFromCombination converts a string like "UDLR" into a slice of Arrow structs.
@@ -392,6 +2195,8 @@ func arrowSequenceFromCombination(sequence string) []Arrow {
 	for _, char := range sequence {
 		if arrow, ok := arrowsMap[char]; ok {
 			result = append(result, arrow)
+		} else if char == checkpointMarker && len(result) > 0 {
+			result[len(result)-1].Checkpoint = true
 		}
 	}
 	return result
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nsf/termbox-go"
+)
+
+func writeTemp(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadCombinationsBOM(t *testing.T) {
+	path := writeTemp(t, "bom.json", []byte("\xef\xbb\xbf"+`[{"name":"Reinforce","sequence":"UDRLU"}]`))
+	combos, err := loadCombinations(path)
+	if err != nil {
+		t.Fatalf("loadCombinations with a BOM: %v", err)
+	}
+	if len(combos) != 1 || combos[0].Name != "Reinforce" {
+		t.Fatalf("got %+v, want the one Reinforce combo", combos)
+	}
+}
+
+func TestLoadCombinationsInvalidUTF8(t *testing.T) {
+	path := writeTemp(t, "latin1.json", []byte(`[{"name":"R`+"\xe9"+`inforce","sequence":"UDRLU"}]`))
+	_, err := loadCombinations(path)
+	if err == nil || !strings.Contains(err.Error(), "not valid UTF-8") {
+		t.Fatalf("got error %v, want the UTF-8 encoding error", err)
+	}
+}
+
+func TestPromptUsernameEmpty(t *testing.T) {
+	console = io.Discard
+	defer func() { console = os.Stderr }()
+	noEnv := func(string) string { return "" }
+	if got := promptUsername(strings.NewReader("\n"), noEnv); got != defaultUsername {
+		t.Errorf("Enter with no name: got %q, want %q", got, defaultUsername)
+	}
+	if got := promptUsername(strings.NewReader(""), noEnv); got != defaultUsername {
+		t.Errorf("empty stdin: got %q, want %q", got, defaultUsername)
+	}
+}
+
+func TestRecordScoreNeverStoresEmptyName(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	for _, user := range []string{"", "   "} {
+		if err := recordScore(runResult{User: user, Mode: "json", Score: 10}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := loadScores()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	for _, e := range entries {
+		if e.Name != defaultUsername {
+			t.Errorf("stored name %q, want %q", e.Name, defaultUsername)
+		}
+	}
+}
+
+func TestConfigValueLargeNumbers(t *testing.T) {
+	for _, tc := range []struct {
+		flag  string
+		value json.Number
+		want  string
+	}{
+		{"count", "1000000", "1000000"},
+		{"settle", "1000000000", "1s"},
+	} {
+		if got := configValue(flag.Lookup(tc.flag), tc.value); got != tc.want {
+			t.Errorf("%s = %s: got %q, want %q", tc.flag, tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestNoQuitBlocksTab(t *testing.T) {
+	defer func(old bool) { *noQuit = old }(*noQuit)
+	tab := termbox.Event{Type: termbox.EventKey, Key: termbox.KeyTab}
+	*noQuit = false
+	if !isSkipKey(tab) || isBlockedQuit(tab) {
+		t.Error("Tab should skip without -no-quit")
+	}
+	*noQuit = true
+	if isSkipKey(tab) {
+		t.Error("Tab skipped a combo under -no-quit")
+	}
+	if !isBlockedQuit(tab) {
+		t.Error("Tab under -no-quit should be reported as a blocked key")
+	}
+}
+
+func TestCorruptScoresKeepBackups(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	console = io.Discard
+	defer func() { console = os.Stderr }()
+
+	for i := 1; i <= 2; i++ {
+		if err := os.WriteFile(scoresFile, []byte("{not json"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadScores(); !errors.Is(err, errCorruptScores) {
+			t.Fatalf("loadScores: got %v, want errCorruptScores", err)
+		}
+		if !fileExists(scoresFile) {
+			t.Fatal("loadScores moved the corrupt file aside")
+		}
+		if err := recordScore(runResult{User: "bob", Mode: "json", Score: 10}); err != nil {
+			t.Fatal(err)
+		}
+		if !fileExists(fmt.Sprintf("%s.corrupt.%d", scoresFile, i)) {
+			t.Fatalf("backup %d missing", i)
+		}
+	}
+}
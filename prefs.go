@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/nsf/termbox-go"
+)
+
+// prefsFile keeps the settings toggled during play for the next session.
+const prefsFile = "prefs.json"
+
+// preferences are the settings players can change mid-run. The zero value
+// is the default: sound unmuted and full feedback. Sound is only ever the
+// -bell and -progress-sound cues the player asked for.
+type preferences struct {
+	Muted bool   `json:"muted"`           // silence -bell and -progress-sound
+	Terse bool   `json:"terse"`           // hide the "Correct!" and "Wrong key" messages
+	Theme string `json:"theme,omitempty"` // name of the theme last picked with 't'
+}
+
+// prefs holds the current preferences, loaded at startup.
+var prefs preferences
+
+// loadPrefs reads prefsFile into prefs. A missing or unreadable file leaves
+// the defaults in place.
+func loadPrefs() {
+	data, err := os.ReadFile(prefsFile)
+	if err != nil {
+		return
+	}
+	json.Unmarshal(data, &prefs)
+}
+
+// savePrefs writes prefs to prefsFile.
+func savePrefs() error {
+	data, err := json.MarshalIndent(prefs, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(prefsFile, data)
+}
+
+// handleToggle flips a preference if ev is one of the toggle keys, 'm' for
+// sound, 'v' for verbose feedback and 't' to cycle themes, and saves it. It returns a message
+// describing the new state, and false if ev isn't a toggle. A key bound to
+// an arrow with -remap is never a toggle.
+func handleToggle(ev termbox.Event) (string, bool) {
+	if ev.Type != termbox.EventKey || ev.Ch == 0 {
+		return "", false
+	}
+	for _, arrow := range arrowsMap {
+		if arrow.Ch == ev.Ch {
+			return "", false
+		}
+	}
+	var msg string
+	switch ev.Ch {
+	case 'm', 'M':
+		prefs.Muted = !prefs.Muted
+		msg = "Sound on"
+		if prefs.Muted {
+			msg = "Sound off"
+		}
+	case 'v', 'V':
+		prefs.Terse = !prefs.Terse
+		msg = "Verbose feedback on"
+		if prefs.Terse {
+			msg = "Verbose feedback off"
+		}
+	case 't', 'T':
+		msg = "Theme: " + nextTheme().Name
+	default:
+		return "", false
+	}
+	// A replay's toggles are the recording's, not this player's choices.
+	if replaying {
+		return msg, true
+	}
+	if err := savePrefs(); err != nil {
+		msg += " (not saved: " + err.Error() + ")"
+	}
+	return msg, true
+}
+
+// feedback returns msg, or nothing when verbose feedback is off.
+func feedback(msg string) string {
+	if prefs.Terse {
+		return ""
+	}
+	return msg
+}
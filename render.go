@@ -0,0 +1,267 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nsf/termbox-go"
+)
+
+// arrowGap is the number of blank columns between two arrows.
+const arrowGap = 3
+
+// Renderer shows the in-game display. Open is called before a run starts
+// and Close once it ends.
+type Renderer interface {
+	Open() error
+	Close()
+	Render(f frame)
+}
+
+// termboxRenderer is the Renderer that draws in the terminal.
+type termboxRenderer struct{}
+
+// Open initialises termbox.
+func (termboxRenderer) Open() error {
+	return termbox.Init()
+}
+
+// Close restores the terminal.
+func (termboxRenderer) Close() {
+	termbox.Close()
+}
+
+// Render draws f and shows it. The first frame of a combo repaints the whole
+// terminal, wiping any stray text printed since the previous frame.
+func (termboxRenderer) Render(f frame) {
+	if f.Bell {
+		fmt.Fprint(console, "\a")
+	}
+	if f.Tick > 0 {
+		fmt.Fprint(console, tickSound(f.Tick))
+	}
+	renderFrame(f)
+	if f.Fresh {
+		termbox.Sync()
+	} else {
+		termbox.Flush()
+	}
+}
+
+// frame is one screen of the in-game display.
+type frame struct {
+	Title    string
+	Score    int
+	Pool     poolProgress
+	Status   []string // mode-specific header lines, such as the timers
+	Sequence []Arrow
+	Entered  int           // how many arrows have been entered so far, in input order
+	Hint     int           // input position of the arrow to underline as a hint, or -1
+	Feedback string        // result of the last key press
+	Shake    bool          // draw everything one cell to the right, for -shake
+	Bell     bool          // sound the bell once, for a wrong key
+	Upcoming []combination // the next combos, drawn dimmed below, for -lookahead
+	Tick     int           // arrows entered, to sound a rising tick for -progress-sound; 0 for none
+	Trail    string        // recent presses, for -trail
+	Fresh    bool          // first frame of a new combo
+}
+
+// wrongKeyBell reports whether a wrong key rings the bell: only with -bell,
+// and not while sound is muted.
+func wrongKeyBell() bool {
+	return *bell && !prefs.Muted
+}
+
+// progressTick returns the frame Tick for the entered-th correct arrow: 0
+// unless -progress-sound is on and sound isn't muted.
+func progressTick(entered int) int {
+	if !*progressSound || prefs.Muted {
+		return 0
+	}
+	return entered
+}
+
+// tickSound returns the terminal output for a progress tick. The Linux
+// console can set the bell's pitch and length, so there each tick is a
+// semitone higher than the last; other terminals just get the plain bell.
+func tickSound(tick int) string {
+	if os.Getenv("TERM") != "linux" {
+		return "\a"
+	}
+	hz := 440 * math.Pow(2, float64(min(tick, 24)-1)/12)
+	// Set the pitch and a short length, ring, then restore the defaults.
+	return fmt.Sprintf("\x1b[10;%d]\x1b[11;40]\a\x1b[10]\x1b[11]", int(hz))
+}
+
+// frameHeader returns the text lines shown above the arrows of f.
+func frameHeader(f frame) []string {
+	header := []string{
+		"Action: " + f.Title,
+		fmt.Sprintf("Current Score: %d", f.Score),
+	}
+	if line := poolProgressLine(f.Pool); line != "" {
+		header = append(header, line)
+	}
+	header = append(header, inputModeLines()...)
+	header = append(header, ghostLines()...)
+	header = append(header, livesLines()...)
+	return append(header, f.Status...)
+}
+
+// renderFrame draws f into the termbox back buffer. Callers flush it.
+func renderFrame(f frame) {
+	termbox.Clear(termbox.ColorDefault, termbox.ColorDefault)
+	header := frameHeader(f)
+
+	offset := 0
+	if f.Shake {
+		offset = 1
+	}
+	for y, line := range header {
+		drawString(offset, y, line, termbox.ColorDefault, termbox.ColorDefault)
+	}
+
+	y := len(header)
+	x := 0
+	width, _ := termbox.Size()
+	if w := arrowsWidth(f.Sequence); w > width {
+		x = -scrollOffset(f, width)
+	} else if *rtl {
+		x = width - w - 2
+	}
+	printArrows(x+offset, y, f.Sequence, f.Entered, f.Hint)
+	drawString(offset, y+7, f.Feedback, termbox.ColorDefault, termbox.ColorDefault)
+	drawString(offset, y+8, f.Trail, currentTheme().Done, termbox.ColorDefault)
+
+	// Upcoming combos are drawn as if already entered, so they stay dim.
+	y += 9
+	for _, combo := range f.Upcoming {
+		seq := arrowSequenceFromCombination(combo.Sequence)
+		drawString(offset, y, "Next: "+combo.Name, currentTheme().Done, termbox.ColorDefault)
+		x := offset
+		if w := arrowsWidth(seq); *rtl && w < width {
+			x = width - w - 2 + offset
+		}
+		printArrows(x, y+1, seq, len(seq), -1)
+		y += 7
+	}
+}
+
+// printArrows draws the arrow art of sequence with its top-left corner at
+// (x, y), in display order. Arrows are coloured by the current theme
+// according to whether they have been entered: entered ones are dimmed, the
+// next one stands out and the rest stay bright. The arrow at input position
+// hint is underlined.
+func printArrows(x, y int, sequence []Arrow, entered, hint int) {
+	th := currentTheme()
+	for _, n := range drawOrder(len(sequence)) {
+		pos := displayIndex(len(sequence), n)
+		fg := th.Upcoming
+		switch {
+		case pos < entered:
+			fg = th.Done
+		case pos == entered:
+			fg = th.Next
+		}
+
+		parts := strings.Split(artFor(sequence[n]), "\n")
+		width := len([]rune(parts[0]))
+		for row, part := range parts {
+			drawString(x, y+row, part, fg, termbox.ColorDefault)
+		}
+		if pos == hint {
+			drawString(x, y+len(parts), strings.Repeat("▔", width), th.Next, termbox.ColorDefault)
+		}
+		x += width + arrowGap
+	}
+}
+
+// scrollOffset returns how many columns to scroll a sequence too wide for
+// the terminal so the next arrow sits about a third of the way across.
+func scrollOffset(f frame, width int) int {
+	n := len(f.Sequence)
+	var before []Arrow
+	for _, i := range drawOrder(n) {
+		if displayIndex(n, i) == f.Entered {
+			break
+		}
+		before = append(before, f.Sequence[i])
+	}
+	offset := arrowsWidth(before) - width/3
+	if max := arrowsWidth(f.Sequence) - width; offset > max {
+		offset = max
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return offset
+}
+
+// arrowsWidth returns the number of columns printArrows uses for sequence.
+func arrowsWidth(sequence []Arrow) int {
+	width := 0
+	for i, arrow := range sequence {
+		if i > 0 {
+			width += arrowGap
+		}
+		width += len([]rune(strings.SplitN(artFor(arrow), "\n", 2)[0]))
+	}
+	return width
+}
+
+// drawOrder returns the indexes of a sequence of n arrows in the order they
+// are drawn from left to right: first to last, or last to first with -rtl.
+func drawOrder(n int) []int {
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+		if *rtl {
+			order[i] = n - 1 - i
+		}
+	}
+	return order
+}
+
+// timedStatus returns the timer lines shown by the timed mode.
+func timedStatus(overallDeadline, comboStart time.Time) []string {
+	return []string{
+		fmt.Sprintf("Overall Time Remaining: %.1f seconds", time.Until(overallDeadline).Seconds()),
+		fmt.Sprintf("Combo Time Elapsed: %.2f seconds", time.Since(comboStart).Seconds()),
+	}
+}
+
+// poolProgressLine returns the pool completion line, or "" unless the mode
+// plays the whole pool.
+func poolProgressLine(pool poolProgress) string {
+	if pool.Total == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Pool progress: %d%% (%d/%d combos cleared)", pool.Cleared*100/pool.Total, pool.Cleared, pool.Total)
+}
+
+// inputModeLines returns reminders for an active reverse input mode or remapped layout.
+func inputModeLines() []string {
+	var lines []string
+	if *reverse {
+		lines = append(lines, "Input: REVERSE (enter the last arrow first)")
+	}
+	if *remap != "" {
+		var binds []string
+		for _, dir := range []rune{'U', 'D', 'L', 'R'} {
+			binds = append(binds, fmt.Sprintf("%c=%s", dir, keyName(arrowsMap[dir])))
+		}
+		lines = append(lines, "Keys remapped: "+strings.Join(binds, " "))
+	}
+	return lines
+}
+
+// drawString writes s into the termbox back buffer starting at (x, y).
+func drawString(x, y int, s string, fg, bg termbox.Attribute) {
+	for _, r := range s {
+		termbox.SetCell(x, y, r, fg, bg)
+		x++
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// event is one line of a run log written with -log: the result of one combo.
+type event struct {
+	Type     string  `json:"type"` // always "combo" for now
+	Index    int     `json:"index"`
+	Combo    string  `json:"combo"`
+	Outcome  outcome `json:"outcome"`
+	Seconds  float64 `json:"seconds"`
+	Score    int     `json:"score"`
+	Mistakes int     `json:"mistakes,omitempty"`
+	Arrows   int     `json:"arrows,omitempty"` // arrows entered correctly the first time, so -strict re-entries don't count
+	AtMS     int64   `json:"at_ms"`            // when the combo ended, since the run started
+}
+
+// runLog collects the events of the current run.
+var runLog []event
+
+// runStart is when the current run started; playMode sets it.
+var runStart time.Time
+
+// logCombo appends the result of a combo to runLog and tallies it for the
+// player's stats.
+func logCombo(name string, o outcome, d time.Duration, score, mistakes, arrows int) {
+	runLog = append(runLog, event{
+		Type:     "combo",
+		Index:    len(runLog),
+		Combo:    name,
+		Outcome:  o,
+		Seconds:  d.Seconds(),
+		Score:    score,
+		Mistakes: mistakes,
+		Arrows:   arrows,
+		AtMS:     time.Since(runStart).Milliseconds(),
+	})
+	tallyCombo(name, o, d, mistakes)
+	runArrows += arrows
+	if ghost != nil && o == outcomeCleared {
+		ghost.cleared()
+	}
+}
+
+// printComboEfficiency prints each logged combo's efficiency as a table, in
+// play order, for the modes without timed mode's breakdown.
+func printComboEfficiency(w io.Writer, events []event) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "#\tCombo\tOutcome\tMistakes\tEfficiency")
+	for i, ev := range events {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%.0f%%\n", fmtInt(i+1), ev.Combo, ev.Outcome, fmtInt(ev.Mistakes), efficiency(ev.Arrows, ev.Mistakes)*100)
+	}
+	tw.Flush()
+}
+
+// UnmarshalText decodes an outcome written by MarshalText.
+func (o *outcome) UnmarshalText(text []byte) error {
+	for _, candidate := range []outcome{outcomeCleared, outcomeFailed, outcomeSkipped} {
+		if candidate.String() == string(text) {
+			*o = candidate
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown outcome %q", text)
+}
+
+// writeRunLog saves events to filename as JSON Lines.
+func writeRunLog(filename string, events []event) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, ev := range events {
+		if err := enc.Encode(ev); err != nil {
+			return err
+		}
+	}
+	return writeFileAtomic(filename, buf.Bytes())
+}
+
+// readRunLog loads a run log written by writeRunLog.
+func readRunLog(filename string) ([]event, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var events []event
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var ev event
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", filename, line, err)
+		}
+		events = append(events, ev)
+	}
+	return events, scanner.Err()
+}
+
+// diffRow pairs up the same combo from two runs. A nil side means the combo
+// does not appear in that run.
+type diffRow struct {
+	Combo string
+	A, B  *event
+}
+
+// diffRuns aligns the combos of two runs by name. The n-th occurrence of a
+// name in a is paired with the n-th occurrence in b, so runs over the same
+// combos line up exactly; combos found in only one run get a row of their own.
+// Rows follow the order of a, then the unmatched combos of b.
+func diffRuns(a, b []event) []diffRow {
+	used := make([]bool, len(b))
+	var rows []diffRow
+	for i := range a {
+		row := diffRow{Combo: a[i].Combo, A: &a[i]}
+		for j := range b {
+			if !used[j] && b[j].Combo == a[i].Combo {
+				used[j] = true
+				row.B = &b[j]
+				break
+			}
+		}
+		rows = append(rows, row)
+	}
+	for j := range b {
+		if !used[j] {
+			rows = append(rows, diffRow{Combo: b[j].Combo, B: &b[j]})
+		}
+	}
+	return rows
+}
+
+// printDiff writes a side-by-side comparison of rows to w, marking which run
+// was faster on each combo both runs cleared.
+func printDiff(w io.Writer, nameA, nameB string, rows []diffRow) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Combo\t%s\t\t%s\t\tBetter\n", nameA, nameB)
+
+	side := func(ev *event) (string, string) {
+		if ev == nil {
+			return "-", "absent"
+		}
+		return fmt.Sprintf("%.2fs", ev.Seconds), ev.Outcome.String()
+	}
+	winsA, winsB := 0, 0
+	for _, row := range rows {
+		timeA, outA := side(row.A)
+		timeB, outB := side(row.B)
+		better := ""
+		if row.A != nil && row.B != nil {
+			clearedA := row.A.Outcome == outcomeCleared
+			clearedB := row.B.Outcome == outcomeCleared
+			switch {
+			case clearedA && (!clearedB || row.A.Seconds < row.B.Seconds):
+				better, winsA = "◀ "+nameA, winsA+1
+			case clearedB && (!clearedA || row.B.Seconds < row.A.Seconds):
+				better, winsB = nameB+" ▶", winsB+1
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", row.Combo, timeA, outA, timeB, outB, better)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "%s was better on %d combos, %s on %d.\n", nameA, winsA, nameB, winsB)
+}
+
+// runDiff implements -diff=a.jsonl,b.jsonl.
+func runDiff(spec string) error {
+	fileA, fileB, ok := strings.Cut(spec, ",")
+	if !ok {
+		return fmt.Errorf("expected two run logs separated by a comma")
+	}
+	a, err := readRunLog(fileA)
+	if err != nil {
+		return err
+	}
+	b, err := readRunLog(fileB)
+	if err != nil {
+		return err
+	}
+	printDiff(os.Stdout, fileA, fileB, diffRuns(a, b))
+	return nil
+}
+
+// clearedSplits returns when each cleared combo in events ended, in ms.
+func clearedSplits(events []event) []int64 {
+	var splits []int64
+	for _, ev := range events {
+		if ev.Outcome == outcomeCleared {
+			splits = append(splits, ev.AtMS)
+		}
+	}
+	return splits
+}
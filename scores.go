@@ -0,0 +1,242 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// scoresFile is the local leaderboard.
+const scoresFile = "scores.json"
+
+// scoreEntry is one finished run on the leaderboard.
+type scoreEntry struct {
+	Name    string    `json:"name"`
+	Mode    string    `json:"mode"`
+	Score   int       `json:"score"`
+	Seconds float64   `json:"seconds"`
+	Date    time.Time `json:"date"`
+}
+
+// anonKeyFile holds this install's random key for anonHandle, next to
+// scoresFile. Without it a handle could be reversed by hashing a list of
+// likely usernames.
+const anonKeyFile = "anon.key"
+
+// anonKey is anonKeyFile's key once anonHandle has needed it.
+var anonKey []byte
+
+// anonHandle returns the handle stored instead of name with -anon: a keyed
+// hash of the name, so one player's runs still group together. It is a
+// pseudonym, not anonymity: anyone who can read anonKeyFile, or who sees a
+// player's runs, can still tell whose handle it is.
+func anonHandle(name string) string {
+	mac := hmac.New(sha256.New, loadAnonKey())
+	mac.Write([]byte(name))
+	return "anon-" + hex.EncodeToString(mac.Sum(nil)[:3])
+}
+
+// loadAnonKey returns anonKey, reading anonKeyFile or creating it with a
+// fresh random key. If the key can't be saved, it warns and uses a key for
+// this session only, whose handles won't match later sessions'.
+func loadAnonKey() []byte {
+	if anonKey != nil {
+		return anonKey
+	}
+	if data, err := os.ReadFile(anonKeyFile); err == nil {
+		if key, err := hex.DecodeString(strings.TrimSpace(string(data))); err == nil && len(key) > 0 {
+			anonKey = key
+			return anonKey
+		}
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	// Readable by this user only, since the key is what keeps handles opaque.
+	if err := os.WriteFile(anonKeyFile, []byte(hex.EncodeToString(key)+"\n"), 0o600); err != nil {
+		fmt.Fprintf(console, "Warning: could not save %s (%v); anonymous handles will differ next session.\n", anonKeyFile, err)
+	}
+	anonKey = key
+	return anonKey
+}
+
+// errCorruptScores is returned by loadScores for a file it can't parse.
+var errCorruptScores = errors.New("corrupt")
+
+// loadScores reads the leaderboard. A missing file is an empty board. A
+// file that can't be parsed is an errCorruptScores error and is left as it
+// is; only recordScore moves it aside.
+func loadScores() ([]scoreEntry, error) {
+	data, err := os.ReadFile(scoresFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var entries []scoreEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("%s is %w (%v)", scoresFile, errCorruptScores, err)
+	}
+	return entries, nil
+}
+
+// moveAside renames filename to the first free filename.corrupt.N and
+// returns that name, so earlier backups are never overwritten.
+func moveAside(filename string) (string, error) {
+	for n := 1; ; n++ {
+		backup := fmt.Sprintf("%s.corrupt.%d", filename, n)
+		if _, err := os.Stat(backup); errors.Is(err, fs.ErrNotExist) {
+			return backup, os.Rename(filename, backup)
+		}
+	}
+}
+
+// recordScore adds r to the leaderboard, under an anonymous handle with -anon.
+// A corrupt leaderboard is moved aside with a warning and started afresh,
+// so one bad write can't block saving for good.
+func recordScore(r runResult) error {
+	entries, err := loadScores()
+	if errors.Is(err, errCorruptScores) {
+		backup, merr := moveAside(scoresFile)
+		if merr != nil {
+			return fmt.Errorf("%w and could not be moved aside: %v", err, merr)
+		}
+		fmt.Fprintf(console, "Warning: %v; saved it as %s and started a new leaderboard.\n", err, backup)
+	} else if err != nil {
+		return err
+	}
+	name := strings.TrimSpace(r.User)
+	if name == "" {
+		name = defaultUsername
+	}
+	if *anon {
+		name = anonHandle(name)
+	}
+	entries = append(entries, scoreEntry{Name: name, Mode: r.Mode, Score: r.Score, Seconds: r.Elapsed, Date: time.Now()})
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(scoresFile, data)
+}
+
+// leaderboardSize is how many entries the leaderboard shows per mode.
+const leaderboardSize = 10
+
+// printLeaderboard writes the best runs of each mode to w, then the speed
+// ladder's board. With -anon every
+// name is shown as a handle, so a kiosk's board never shows real names.
+func printLeaderboard(w io.Writer) error {
+	entries, err := loadScores()
+	if err != nil {
+		return err
+	}
+	ladder, err := loadLadder()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 && len(ladder) == 0 {
+		fmt.Fprintln(w, "No scores recorded yet.")
+		return nil
+	}
+	byMode := map[string][]scoreEntry{}
+	var modes []string
+	for _, e := range entries {
+		if byMode[e.Mode] == nil {
+			modes = append(modes, e.Mode)
+		}
+		byMode[e.Mode] = append(byMode[e.Mode], e)
+	}
+	sort.Strings(modes)
+	for i, mode := range modes {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		board := byMode[mode]
+		sort.SliceStable(board, func(i, j int) bool { return rankedBefore(board[i], board[j]) })
+		fmt.Fprintf(w, "%s\n", mode)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "#\tName\tScore\tTime\tDate")
+		for rank, e := range board[:min(leaderboardSize, len(board))] {
+			name := e.Name
+			if *anon && !isAnonHandle(name) {
+				name = anonHandle(name)
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%ss\t%s\n", rank+1, name, fmtInt(e.Score), fmtFloat(e.Seconds, 2), e.Date.Local().Format("2006-01-02"))
+		}
+		tw.Flush()
+	}
+	if len(ladder) > 0 {
+		if len(modes) > 0 {
+			fmt.Fprintln(w)
+		}
+		printLadder(w, ladder)
+	}
+	return nil
+}
+
+// rankedBefore orders leaderboard entries: higher score first, then the
+// faster run, then the earlier one, so equal runs always list the same way.
+func rankedBefore(a, b scoreEntry) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	if a.Seconds != b.Seconds {
+		return a.Seconds < b.Seconds
+	}
+	return a.Date.Before(b.Date)
+}
+
+// isAnonHandle reports whether name is already an anonymous handle.
+func isAnonHandle(name string) bool {
+	return len(name) == len("anon-")+6 && name[:5] == "anon-"
+}
+
+// showRecordsBanner prints user's personal bests, from scoresFile and
+// statsFile, so a session starts with its targets in view. It prints
+// nothing for a player with no records yet.
+func showRecordsBanner(user string) {
+	var lines []string
+	if entries, err := loadScores(); err == nil {
+		var best *scoreEntry
+		for i, e := range entries {
+			// Only a player who has used -anon has a key to match handles with.
+			mine := e.Name == user || isAnonHandle(e.Name) && fileExists(anonKeyFile) && e.Name == anonHandle(user)
+			if mine && (best == nil || rankedBefore(e, *best)) {
+				best = &entries[i]
+			}
+		}
+		if best != nil {
+			lines = append(lines, fmt.Sprintf("Best score:     %s (%s)", fmtInt(best.Score), best.Mode))
+		}
+	}
+	if all, err := loadStats(); err == nil && all[user] != nil {
+		rec := all[user].Records
+		if rec.FastestCombo != "" {
+			lines = append(lines, fmt.Sprintf("Fastest combo:  %s in %ss", rec.FastestCombo, fmtFloat(rec.FastestSeconds, 2)))
+		}
+		if rec.LongestStreak > 0 {
+			lines = append(lines, fmt.Sprintf("Longest streak: %s clean combos", fmtInt(rec.LongestStreak)))
+		}
+	}
+	if len(lines) == 0 {
+		return
+	}
+	logf("== Records for %s ==\n", user)
+	for _, line := range lines {
+		logf("%s\n", line)
+	}
+	logf("\n")
+}
@@ -0,0 +1,441 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// statsFile persists each player's long-term accuracy between runs.
+const statsFile = "stats.json"
+
+// dirStat counts how often a direction was entered correctly when it was
+// the next arrow, and how often a wrong key was pressed instead.
+type dirStat struct {
+	Hits   int `json:"hits"`
+	Misses int `json:"misses"`
+}
+
+// accuracy is the share of attempts at the direction that were correct.
+func (s dirStat) accuracy() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 1
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// playerStats is one player's entry in statsFile.
+type playerStats struct {
+	Directions  map[string]dirStat   `json:"directions"`
+	Combos      map[string]comboStat `json:"combos,omitempty"`
+	Transitions map[string]transStat `json:"transitions,omitempty"` // keyed by the two directions, e.g. "LU"
+	Perfect     int                  `json:"perfect_combos"`        // timed combos cleared within the top bonus tier
+	Records     playerRecords        `json:"records"`
+	Cleared     int                  `json:"cleared_combos"`
+	DayStreak   int                  `json:"day_streak"` // consecutive days played, up to LastPlayed
+	LastPlayed  time.Time            `json:"last_played"`
+}
+
+// playerRecords are a player's personal bests across every run.
+type playerRecords struct {
+	FastestCombo   string  `json:"fastest_combo,omitempty"`
+	FastestSeconds float64 `json:"fastest_seconds,omitempty"`
+	LongestStreak  int     `json:"longest_streak,omitempty"` // combos cleared in a row without a wrong key
+}
+
+// runDirections tallies the current run's per-direction accuracy; playMode
+// resets it and merges it into statsFile when the run ends.
+var runDirections = map[rune]dirStat{}
+
+// comboStat counts how often a combo was played and how often it went
+// wrong: failed, skipped or cleared only after wrong keys.
+type comboStat struct {
+	Played int `json:"played"`
+	Failed int `json:"failed"`
+}
+
+// runCombos tallies the current run's combos, like runDirections.
+var runCombos = map[string]comboStat{}
+
+// packNames are the combo names in the pack the current run plays, noted by
+// loadPack. Only those are tallied in runCombos, so boss, gauntlet and
+// random titles stay out of the history -stats and -revenge read.
+var packNames map[string]bool
+
+// runRecords are the current run's bests, like runDirections, and runStreak
+// the clean combos cleared in a row so far.
+var (
+	runRecords playerRecords
+	runStreak  int
+)
+
+// tallyCombo records one play of the named combo, which took d.
+func tallyCombo(name string, o outcome, d time.Duration, mistakes int) {
+	clean := o == outcomeCleared && mistakes == 0
+	if clean {
+		runStreak++
+		runRecords.LongestStreak = max(runRecords.LongestStreak, runStreak)
+	} else {
+		runStreak = 0
+	}
+	if packNames[name] {
+		s := runCombos[name]
+		s.Played++
+		if !clean {
+			s.Failed++
+		}
+		runCombos[name] = s
+	}
+	if o == outcomeCleared && (runRecords.FastestCombo == "" || d.Seconds() < runRecords.FastestSeconds) {
+		runRecords.FastestCombo, runRecords.FastestSeconds = name, d.Seconds()
+	}
+}
+
+// transStat measures one direction-to-direction transition: how long the
+// second key took after the first, and how often a wrong key came instead.
+type transStat struct {
+	Hits    int   `json:"hits"`
+	Misses  int   `json:"misses"`
+	TotalMS int64 `json:"total_ms"` // summed over the hits
+}
+
+// runTransitions tallies the current run's transitions, like runDirections.
+var runTransitions = map[string]transStat{}
+
+// tallyTransition records the move into order[i] from the arrow before it,
+// taking d when hit. The first arrow of a combo has no transition.
+func tallyTransition(order []Arrow, i int, hit bool, d time.Duration) {
+	if i == 0 {
+		return
+	}
+	key := string([]rune{order[i-1].Dir, order[i].Dir})
+	s := runTransitions[key]
+	if hit {
+		s.Hits++
+		s.TotalMS += d.Milliseconds()
+	} else {
+		s.Misses++
+	}
+	runTransitions[key] = s
+}
+
+// tallyArrow records a hit or a miss on the expected arrow.
+func tallyArrow(expected Arrow, hit bool) {
+	s := runDirections[expected.Dir]
+	if hit {
+		s.Hits++
+	} else {
+		s.Misses++
+	}
+	runDirections[expected.Dir] = s
+}
+
+// loadStats reads statsFile. A missing file yields empty stats.
+func loadStats() (map[string]*playerStats, error) {
+	all := map[string]*playerStats{}
+	data, err := os.ReadFile(statsFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return all, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+// saveStats writes all players' stats to statsFile.
+func saveStats(all map[string]*playerStats) error {
+	data, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(statsFile, data)
+}
+
+// statsFor returns user's entry in all, creating it if needed.
+func statsFor(all map[string]*playerStats, user string) *playerStats {
+	ps := all[user]
+	if ps == nil {
+		ps = &playerStats{}
+		all[user] = ps
+	}
+	if ps.Directions == nil {
+		ps.Directions = map[string]dirStat{}
+	}
+	if ps.Combos == nil {
+		ps.Combos = map[string]comboStat{}
+	}
+	if ps.Transitions == nil {
+		ps.Transitions = map[string]transStat{}
+	}
+	return ps
+}
+
+// recordRunStats merges the finished run r and its tallies into user's
+// stats, and returns them.
+func recordRunStats(user string, r runResult) (*playerStats, error) {
+	all, err := loadStats()
+	if err != nil {
+		return nil, err
+	}
+	ps := statsFor(all, user)
+	for dir, s := range runDirections {
+		total := ps.Directions[string(dir)]
+		total.Hits += s.Hits
+		total.Misses += s.Misses
+		ps.Directions[string(dir)] = total
+	}
+	for name, s := range runCombos {
+		total := ps.Combos[name]
+		total.Played += s.Played
+		total.Failed += s.Failed
+		ps.Combos[name] = total
+	}
+	for key, s := range runTransitions {
+		total := ps.Transitions[key]
+		total.Hits += s.Hits
+		total.Misses += s.Misses
+		total.TotalMS += s.TotalMS
+		ps.Transitions[key] = total
+	}
+	ps.Perfect += r.Perfect
+	for _, o := range r.Outcomes {
+		if o == outcomeCleared {
+			ps.Cleared++
+		}
+	}
+	if rec := runRecords; rec.FastestCombo != "" && (ps.Records.FastestCombo == "" || rec.FastestSeconds < ps.Records.FastestSeconds) {
+		ps.Records.FastestCombo, ps.Records.FastestSeconds = rec.FastestCombo, rec.FastestSeconds
+	}
+	ps.Records.LongestStreak = max(ps.Records.LongestStreak, runRecords.LongestStreak)
+	now := time.Now()
+	switch last := ps.LastPlayed.Local(); {
+	case ps.LastPlayed.IsZero():
+		ps.DayStreak = 1
+	case sameDay(last, now):
+		ps.DayStreak = max(ps.DayStreak, 1)
+	case sameDay(last.AddDate(0, 0, 1), now):
+		ps.DayStreak++
+	default:
+		ps.DayStreak = 1
+	}
+	ps.LastPlayed = now
+	return ps, saveStats(all)
+}
+
+// sameDay reports whether a and b fall on the same local calendar day.
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// minWeakAttempts is how many attempts a direction needs before its
+// accuracy is trusted to pick a weak direction.
+const minWeakAttempts = 10
+
+// weakestDirection returns the direction user enters least accurately, or
+// false when there is not yet enough data to tell.
+func weakestDirection(user string) (rune, bool) {
+	all, err := loadStats()
+	if err != nil || all[user] == nil {
+		return 0, false
+	}
+	var weakest rune
+	worst := 2.0
+	for _, dir := range []rune{'U', 'D', 'L', 'R'} {
+		s := all[user].Directions[string(dir)]
+		if s.Hits+s.Misses < minWeakAttempts {
+			continue
+		}
+		if acc := s.accuracy(); acc < worst {
+			weakest, worst = dir, acc
+		}
+	}
+	// Perfect accuracy everywhere leaves nothing to target.
+	return weakest, weakest != 0 && worst < 1
+}
+
+// directionNames spells out a direction for messages.
+var directionNames = map[rune]string{'U': "UP", 'D': "DOWN", 'L': "LEFT", 'R': "RIGHT"}
+
+// revengeSet builds a run of count combos from pool for -revenge: those user
+// has failed most often first, padded with random others. It returns false
+// when user has no failures on record for any combo in pool.
+func revengeSet(pool []combination, count int, user string) ([]combination, bool) {
+	all, err := loadStats()
+	if err != nil || all[user] == nil {
+		return nil, false
+	}
+	history := all[user].Combos
+	rng.Shuffle(len(pool), func(i, j int) {
+		pool[i], pool[j] = pool[j], pool[i]
+	})
+	// The stable sort keeps the shuffled order among equally failed combos.
+	sort.SliceStable(pool, func(i, j int) bool {
+		return history[pool[i].Name].Failed > history[pool[j].Name].Failed
+	})
+	if len(pool) == 0 || history[pool[0].Name].Failed == 0 {
+		return nil, false
+	}
+	if count > len(pool) {
+		count = len(pool)
+	}
+	selected := pool[:count]
+	failed := 0
+	for _, combo := range selected {
+		if history[combo.Name].Failed > 0 {
+			failed++
+		}
+	}
+	logf("Revenge run: %d of %d combos are ones you have failed before.\n", failed, count)
+	rng.Shuffle(len(selected), func(i, j int) {
+		selected[i], selected[j] = selected[j], selected[i]
+	})
+	return selected, true
+}
+
+// runMistakes is the number of wrong keys pressed in the current run.
+func runMistakes() int {
+	n := 0
+	for _, s := range runDirections {
+		n += s.Misses
+	}
+	return n
+}
+
+// runArrows counts the arrows entered correctly the first time in the
+// current run, as logged by logCombo; playMode resets it.
+var runArrows int
+
+// runEfficiency is the current run's share of key presses that were
+// correct arrows. Arrows entered again after a -strict reset don't count,
+// so mistakes never raise it.
+func runEfficiency() float64 {
+	return efficiency(runArrows, runMistakes())
+}
+
+// Players returning after easeAfter away start random runs easier: each
+// further easeAfter away shortens the opening sequence by another arrow, up
+// to maxEase arrows.
+const (
+	easeAfter = 7 * 24 * time.Hour
+	maxEase   = 3
+)
+
+// welcomeBackEase returns how many arrows to take off the opening random
+// sequences for user, based on the time since they last played.
+func welcomeBackEase(user string) int {
+	all, err := loadStats()
+	if err != nil || all[user] == nil || all[user].LastPlayed.IsZero() {
+		return 0
+	}
+	ease := min(int(time.Since(all[user].LastPlayed)/easeAfter), maxEase)
+	if ease > 0 {
+		logf("Welcome back — easing you in\n")
+	}
+	return ease
+}
+
+// minTransitionSamples is how many hits a transition needs before the heat
+// map shows its timing.
+const minTransitionSamples = 5
+
+// heatColors shade heat map cells from fastest to slowest (ANSI 256-colour
+// backgrounds: green, yellow-green, yellow, orange, red).
+var heatColors = []int{28, 100, 136, 166, 160}
+
+// printStats writes user's lifetime stats to w: per-direction accuracy and
+// a heat map of the average time for each direction-to-direction transition.
+func printStats(w io.Writer, user string) error {
+	all, err := loadStats()
+	if err != nil {
+		return err
+	}
+	ps := all[user]
+	if ps == nil {
+		fmt.Fprintf(w, "No stats recorded for %s yet.\n", user)
+		return nil
+	}
+	fmt.Fprintf(w, "Stats for %s", user)
+	if !ps.LastPlayed.IsZero() {
+		fmt.Fprintf(w, " (last played %s)", ps.LastPlayed.Local().Format("2006-01-02 15:04"))
+	}
+	fmt.Fprintln(w)
+
+	dirs := []rune{'U', 'D', 'L', 'R'}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\nDirection\tAccuracy\tHits\tMisses")
+	for _, dir := range dirs {
+		s := ps.Directions[string(dir)]
+		fmt.Fprintf(tw, "%s\t%.0f%%\t%d\t%d\n", directionNames[dir], s.accuracy()*100, s.Hits, s.Misses)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\nPerfect timed combos: %d\n", ps.Perfect)
+
+	// Rank the measured transitions by average time to pick each cell's shade.
+	avg := map[string]float64{}
+	var slowest string
+	var times []float64
+	for key, s := range ps.Transitions {
+		if s.Hits >= minTransitionSamples {
+			avg[key] = float64(s.TotalMS) / float64(s.Hits)
+			times = append(times, avg[key])
+			if slowest == "" || avg[key] > avg[slowest] {
+				slowest = key
+			}
+		}
+	}
+	if len(avg) == 0 {
+		fmt.Fprintln(w, "\nPlay a few more runs to see your transition heat map.")
+		return nil
+	}
+	sort.Float64s(times)
+	color := isTerminal(w)
+
+	fmt.Fprintln(w, "\nTransition heat map: average ms from the row's key to the column's, misses in brackets")
+	fmt.Fprintf(w, "%-7s", "from\\to")
+	for _, to := range dirs {
+		fmt.Fprintf(w, " %-11s", directionNames[to])
+	}
+	fmt.Fprintln(w)
+	for _, from := range dirs {
+		fmt.Fprintf(w, "%-7s", directionNames[from])
+		for _, to := range dirs {
+			key := string([]rune{from, to})
+			cell := "-"
+			if ms, ok := avg[key]; ok {
+				cell = fmt.Sprintf("%.0f (%d)", ms, ps.Transitions[key].Misses)
+			}
+			cell = fmt.Sprintf(" %-11s", cell)
+			if ms, ok := avg[key]; ok && color {
+				rank := sort.SearchFloat64s(times, ms)
+				shade := heatColors[rank*len(heatColors)/len(times)]
+				cell = fmt.Sprintf("\x1b[48;5;%dm%s\x1b[0m", shade, cell)
+			}
+			fmt.Fprint(w, cell)
+		}
+		fmt.Fprintln(w)
+	}
+	runes := []rune(slowest)
+	fmt.Fprintf(w, "\nSlowest transition: %s→%s (%.0f ms)\n", directionNames[runes[0]], directionNames[runes[1]], avg[slowest])
+	return nil
+}
+
+// isTerminal reports whether w is a terminal, where colour can be used.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// arrowGlyphs are the single-character arrows used in plain-text output,
+// with a bar for a checkpoint marker.
+var arrowGlyphs = map[rune]string{'U': "⬆", 'D': "⬇", 'L': "⬅", 'R': "➡", checkpointMarker: "|"}
+
+// filterCategory returns the combos whose type contains category, ignoring
+// case. An empty category keeps them all.
+func filterCategory(combos []combination, category string) []combination {
+	if category == "" {
+		return combos
+	}
+	var kept []combination
+	for _, combo := range combos {
+		if strings.Contains(strings.ToLower(combo.Type), strings.ToLower(category)) {
+			kept = append(kept, combo)
+		}
+	}
+	return kept
+}
+
+// writeStudySheet writes a printable sheet of combos to path, grouped by
+// category, with each sequence as letters and as arrows.
+func writeStudySheet(path string, combos []combination) error {
+	if len(combos) == 0 {
+		return fmt.Errorf("no combos to write")
+	}
+	groups := map[string][]combination{}
+	nameWidth, seqWidth := 0, 0
+	for _, combo := range combos {
+		category := combo.Type
+		if category == "" {
+			category = "Uncategorised"
+		}
+		groups[category] = append(groups[category], combo)
+		nameWidth = max(nameWidth, len([]rune(combo.Name)))
+		seqWidth = max(seqWidth, len(combo.Sequence))
+	}
+	categories := make([]string, 0, len(groups))
+	for category := range groups {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	var b strings.Builder
+	b.WriteString("HELLDIVERS STRATAGEM STUDY SHEET\n")
+	for _, category := range categories {
+		fmt.Fprintf(&b, "\n%s\n%s\n", category, strings.Repeat("-", len([]rune(category))))
+		for _, combo := range groups[category] {
+			var glyphs []string
+			for _, dir := range combo.Sequence {
+				glyphs = append(glyphs, arrowGlyphs[dir])
+			}
+			// %-*s pads by bytes, so pad names by runes ourselves.
+			pad := strings.Repeat(" ", nameWidth-len([]rune(combo.Name)))
+			fmt.Fprintf(&b, "%s%s  %-*s  %s\n", combo.Name, pad, seqWidth, combo.Sequence, strings.Join(glyphs, " "))
+		}
+	}
+	return writeFileAtomic(path, []byte(b.String()))
+}
+
+// normalizePattern uppercases a -find pattern and strips its spaces, so
+// "u d lr" and "UDLR" search alike.
+func normalizePattern(pattern string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(pattern), ""))
+}
+
+// arrowsOnly returns sequence without its checkpoint markers.
+func arrowsOnly(sequence string) string {
+	return strings.ReplaceAll(sequence, string(checkpointMarker), "")
+}
+
+// findBySequence returns the combos whose sequence contains pattern, those
+// matching it exactly first, each group in file order.
+func findBySequence(combos []combination, pattern string) []combination {
+	pattern = normalizePattern(pattern)
+	var exact, partial []combination
+	for _, combo := range combos {
+		seq := arrowsOnly(combo.Sequence)
+		switch {
+		case seq == pattern:
+			exact = append(exact, combo)
+		case strings.Contains(seq, pattern):
+			partial = append(partial, combo)
+		}
+	}
+	return append(exact, partial...)
+}
+
+// printFound writes the -find results for pattern to stdout.
+func printFound(combos []combination, pattern string) {
+	pattern = normalizePattern(pattern)
+	found := findBySequence(combos, pattern)
+	if len(found) == 0 {
+		fmt.Printf("No combos contain %s.\n", pattern)
+		return
+	}
+	nameWidth := 0
+	for _, combo := range found {
+		nameWidth = max(nameWidth, len([]rune(combo.Name)))
+	}
+	for _, combo := range found {
+		match := "contains"
+		if arrowsOnly(combo.Sequence) == pattern {
+			match = "exact"
+		}
+		pad := strings.Repeat(" ", nameWidth-len([]rune(combo.Name)))
+		fmt.Printf("%s%s  %-8s  %s\n", combo.Name, pad, match, combo.Sequence)
+	}
+}
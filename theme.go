@@ -0,0 +1,79 @@
+package main
+
+import "github.com/nsf/termbox-go"
+
+// theme is a look for the in-game display: the colours of the arrows and,
+// optionally, replacement art for each direction.
+type theme struct {
+	Name     string
+	Done     termbox.Attribute // arrows already entered
+	Next     termbox.Attribute // the arrow to enter now
+	Upcoming termbox.Attribute // the rest
+	Art      map[rune]string   // nil keeps each Arrow's own art
+}
+
+// themes are the looks 't' cycles through, in order. The first is the default.
+var themes = []theme{
+	{
+		Name:     "classic",
+		Done:     termbox.ColorDefault | termbox.AttrDim,
+		Next:     termbox.ColorYellow | termbox.AttrBold,
+		Upcoming: termbox.ColorWhite | termbox.AttrBold,
+	},
+	{
+		Name:     "amber",
+		Done:     termbox.ColorRed,
+		Next:     termbox.ColorYellow | termbox.AttrBold,
+		Upcoming: termbox.ColorYellow,
+	},
+	{
+		Name:     "mono",
+		Done:     termbox.ColorDefault | termbox.AttrDim,
+		Next:     termbox.ColorDefault | termbox.AttrReverse,
+		Upcoming: termbox.ColorDefault,
+	},
+	{
+		Name:     "ascii",
+		Done:     termbox.ColorDefault | termbox.AttrDim,
+		Next:     termbox.ColorYellow | termbox.AttrBold,
+		Upcoming: termbox.ColorWhite | termbox.AttrBold,
+		Art: map[rune]string{
+			'U': "  ^  \n /|\\ \n/ | \\\n  |  \n  |  ",
+			'D': "  |  \n  |  \n\\ | /\n \\|/ \n  v  ",
+			'L': "  /   \n /    \n<-----\n \\    \n  \\   ",
+			'R': "   \\  \n    \\ \n----->\n    / \n   /  ",
+		},
+	},
+}
+
+// currentTheme returns the theme saved in prefs, or the default if it is
+// unknown.
+func currentTheme() theme {
+	for _, t := range themes {
+		if t.Name == prefs.Theme {
+			return t
+		}
+	}
+	return themes[0]
+}
+
+// nextTheme switches prefs to the theme after the current one and returns it.
+func nextTheme() theme {
+	cur := currentTheme()
+	for i, t := range themes {
+		if t.Name == cur.Name {
+			next := themes[(i+1)%len(themes)]
+			prefs.Theme = next.Name
+			return next
+		}
+	}
+	return cur
+}
+
+// artFor returns the art to draw for arrow in the current theme.
+func artFor(arrow Arrow) string {
+	if art, ok := currentTheme().Art[arrow.Dir]; ok {
+		return art
+	}
+	return arrow.Art
+}
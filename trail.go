@@ -0,0 +1,51 @@
+package main
+
+import "strings"
+
+// pressTrail is a ring buffer of the outcomes of the last few arrow key
+// presses, hit or miss, shown beneath the combo with -trail.
+type pressTrail struct {
+	marks []bool
+	next  int  // where the next press goes; the oldest once full
+	full  bool // every slot has been written
+}
+
+// runTrail is the current run's trail; playMode sizes it from -trail.
+var runTrail pressTrail
+
+// reset empties t and makes room for n presses. Zero disables it.
+func (t *pressTrail) reset(n int) {
+	t.marks, t.next, t.full = make([]bool, n), 0, false
+}
+
+// push records a press, dropping the oldest once t is full.
+func (t *pressTrail) push(hit bool) {
+	if len(t.marks) == 0 {
+		return
+	}
+	t.marks[t.next] = hit
+	t.next = (t.next + 1) % len(t.marks)
+	if t.next == 0 {
+		t.full = true
+	}
+}
+
+// String renders the trail oldest first, ✓ for a hit and ✗ for a miss, or
+// "" when nothing has been pressed.
+func (t *pressTrail) String() string {
+	start, n := 0, t.next
+	if t.full {
+		start, n = t.next, len(t.marks)
+	}
+	if n == 0 {
+		return ""
+	}
+	marks := make([]string, n)
+	for i := range marks {
+		marks[i] = "✓"
+		if !t.marks[(start+i)%len(t.marks)] {
+			marks[i] = "✗"
+		}
+	}
+	return "Trail: " + strings.Join(marks, " ")
+}
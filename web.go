@@ -0,0 +1,231 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"sync"
+
+	"github.com/nsf/termbox-go"
+)
+
+//go:embed web
+var webFiles embed.FS
+
+// webKeys maps the KeyboardEvent.key names sent by the browser to termbox keys.
+var webKeys = map[string]termbox.Key{
+	"ArrowUp":    termbox.KeyArrowUp,
+	"ArrowDown":  termbox.KeyArrowDown,
+	"ArrowLeft":  termbox.KeyArrowLeft,
+	"ArrowRight": termbox.KeyArrowRight,
+	"Escape":     termbox.KeyEsc,
+	"Enter":      termbox.KeyEnter,
+	"Tab":        termbox.KeyTab,
+	" ":          termbox.KeySpace,
+}
+
+// webMessage is a message from the browser: a key press, or a request to
+// start a run in one of the menu's modes.
+type webMessage struct {
+	Type string `json:"type"` // "key" or "start"
+	Key  string `json:"key,omitempty"`
+	Mode string `json:"mode,omitempty"`
+	User string `json:"user,omitempty"`
+}
+
+// webArrow is one arrow of a combo as sent to the browser, in display order.
+type webArrow struct {
+	Dir   string `json:"dir"`
+	State string `json:"state"` // "done", "next" or "upcoming"
+	Hint  bool   `json:"hint,omitempty"`
+}
+
+// webFrame is a frame as sent to the browser.
+type webFrame struct {
+	Type     string     `json:"type"`
+	Header   []string   `json:"header"`
+	Arrows   []webArrow `json:"arrows"`
+	Feedback string     `json:"feedback"`
+	Trail    string     `json:"trail,omitempty"`
+	Shake    bool       `json:"shake,omitempty"`
+	Bell     bool       `json:"bell,omitempty"`
+	Tick     int        `json:"tick,omitempty"`
+	Theme    string     `json:"theme"`
+	Upcoming []webCombo `json:"upcoming,omitempty"`
+	RTL      bool       `json:"rtl,omitempty"`
+}
+
+// webCombo is an upcoming combo as sent to the browser for -lookahead.
+type webCombo struct {
+	Name string   `json:"name"`
+	Dirs []string `json:"dirs"` // in display order
+}
+
+// webSession connects one browser to the game engine. It is both the
+// InputSource and the Renderer while the browser's runs are played.
+type webSession struct {
+	ws     *wsConn
+	events chan termbox.Event
+}
+
+// Events returns the key presses received from the browser.
+func (s *webSession) Events() <-chan termbox.Event {
+	return s.events
+}
+
+// Open has nothing to prepare; the browser is already connected.
+func (s *webSession) Open() error {
+	return nil
+}
+
+// Close has nothing to release; the connection outlives a single run.
+func (s *webSession) Close() {}
+
+// Render sends f to the browser.
+func (s *webSession) Render(f frame) {
+	wf := webFrame{
+		Type:     "frame",
+		Header:   frameHeader(f),
+		Feedback: f.Feedback,
+		Trail:    f.Trail,
+		Shake:    f.Shake,
+		Bell:     f.Bell,
+		Tick:     f.Tick,
+		Theme:    currentTheme().Name,
+		RTL:      *rtl,
+	}
+	for _, n := range drawOrder(len(f.Sequence)) {
+		pos := displayIndex(len(f.Sequence), n)
+		state := "upcoming"
+		switch {
+		case pos < f.Entered:
+			state = "done"
+		case pos == f.Entered:
+			state = "next"
+		}
+		wf.Arrows = append(wf.Arrows, webArrow{Dir: string(f.Sequence[n].Dir), State: state, Hint: pos == f.Hint})
+	}
+	for _, combo := range f.Upcoming {
+		seq := arrowSequenceFromCombination(combo.Sequence)
+		wc := webCombo{Name: combo.Name}
+		for _, n := range drawOrder(len(seq)) {
+			wc.Dirs = append(wc.Dirs, string(seq[n].Dir))
+		}
+		wf.Upcoming = append(wf.Upcoming, wc)
+	}
+	s.send(wf)
+}
+
+// send writes v to the browser as JSON. Errors are ignored; a broken
+// connection is noticed by the reader, which ends the session.
+func (s *webSession) send(v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	s.ws.WriteMessage(data)
+}
+
+// deliver queues ev for the game without ever blocking the reader, dropping
+// key presses that arrive while no run is reading them.
+func (s *webSession) deliver(ev termbox.Event) {
+	select {
+	case s.events <- ev:
+	default:
+	}
+}
+
+// serveWeb serves the web UI on addr until the server fails. One browser can
+// play at a time, since the game engine reads its input and draws its frames
+// through the package-level input and screen.
+func serveWeb(addr, user string) error {
+	static, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		return err
+	}
+	var busy sync.Mutex
+
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.FS(static)))
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		if !busy.TryLock() {
+			http.Error(w, "a game is already in progress", http.StatusConflict)
+			return
+		}
+		defer busy.Unlock()
+
+		ws, err := upgradeWebsocket(w, r)
+		if err != nil {
+			logf("WebSocket handshake failed: %s\n", err)
+			return
+		}
+		defer ws.Close()
+		playWebSession(ws, user)
+	})
+
+	logf("Serving the web UI on %s\n", addr)
+	return http.ListenAndServe(addr, mux)
+}
+
+// playWebSession plays runs for one connected browser until it disconnects.
+func playWebSession(ws *wsConn, user string) {
+	s := &webSession{ws: ws, events: make(chan termbox.Event, 16)}
+	starts := make(chan webMessage, 1)
+
+	go func() {
+		defer close(starts)
+		for {
+			data, err := ws.ReadMessage()
+			if err != nil {
+				// Abort any run in progress; Ctrl+C quits even under -no-quit.
+				s.deliver(termbox.Event{Type: termbox.EventKey, Key: termbox.KeyCtrlC})
+				return
+			}
+			var msg webMessage
+			if json.Unmarshal(data, &msg) != nil {
+				continue
+			}
+			switch msg.Type {
+			case "key":
+				if key, ok := webKeys[msg.Key]; ok {
+					s.deliver(termbox.Event{Type: termbox.EventKey, Key: key})
+				} else if r := []rune(msg.Key); len(r) == 1 {
+					s.deliver(termbox.Event{Type: termbox.EventKey, Ch: r[0]})
+				}
+			case "start":
+				select {
+				case starts <- msg:
+				default: // a run is already starting
+				}
+			}
+		}
+	}()
+
+	input, screen = s, s
+	for msg := range starts {
+		name := user
+		if msg.User != "" {
+			name = msg.User
+		}
+		// Drop keys pressed between runs.
+		for len(s.events) > 0 {
+			<-s.events
+		}
+		result, ok := playMode(msg.Mode, name)
+		if !ok {
+			s.send(map[string]string{"type": "error", "error": "unknown mode " + msg.Mode})
+			continue
+		}
+		s.send(struct {
+			Type   string    `json:"type"`
+			Result runResult `json:"result"`
+		}{"result", result})
+		if *webhook != "" {
+			if err := postResult(*webhook, result); err != nil {
+				fmt.Fprintln(console, "Webhook failed:", err)
+			}
+		}
+	}
+}
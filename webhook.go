@@ -0,0 +1,31 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// webhookTimeout bounds how long posting a result may delay the end of a run.
+const webhookTimeout = 3 * time.Second
+
+// postResult sends r as JSON to url with a POST request. It is best effort:
+// the request gives up after webhookTimeout and any non-2xx reply is an error.
+func postResult(url string, r runResult) error {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	client := http.Client{Timeout: webhookTimeout}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}